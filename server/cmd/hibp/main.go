@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/LullNil/authx-go/internal/lib/breach"
+)
+
+func main() {
+	var input, output, kind string
+
+	flag.StringVar(&input, "input", "", "HIBP dump: a HASH:COUNT file ordered by hash, or a directory of range files named by their 5-character prefix")
+	flag.StringVar(&output, "output", "", "path of the index file to write (e.g., './data/pwned-sha1.idx')")
+	flag.StringVar(&kind, "kind", breach.KindSHA1, "hash kind of the dump (sha1, ntlm)")
+	flag.Parse()
+
+	if input == "" {
+		log.Fatal("input is required")
+	}
+	if output == "" {
+		log.Fatal("output is required")
+	}
+
+	out, err := os.Create(output + ".tmp")
+	if err != nil {
+		log.Fatalf("Failed to create index: %v", err)
+	}
+	defer out.Close()
+
+	info, err := os.Stat(input)
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+
+	var n int64
+	if info.IsDir() {
+		n, err = buildFromRanges(input, out, kind)
+	} else {
+		var in *os.File
+		in, err = os.Open(input)
+		if err != nil {
+			log.Fatalf("Failed to open input: %v", err)
+		}
+		defer in.Close()
+		n, err = breach.BuildIndex(in, out, kind)
+	}
+	if err != nil {
+		log.Fatalf("Failed to build index: %v", err)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Fatalf("Failed to write index: %v", err)
+	}
+	if err := os.Rename(output+".tmp", output); err != nil {
+		log.Fatalf("Failed to write index: %v", err)
+	}
+
+	fmt.Printf("Index written to %s (%d hashes)\n", output, n)
+}
+
+// buildFromRanges concatenates range files in prefix order into a single HASH:COUNT stream.
+func buildFromRanges(dir string, out *os.File, kind string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		prefix := strings.ToUpper(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		if !e.IsDir() && len(prefix) == 5 {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToUpper(names[i]) < strings.ToUpper(names[j]) })
+
+	files := make([]string, 0, len(names))
+	prefixes := make([]string, 0, len(names))
+	for _, name := range names {
+		files = append(files, filepath.Join(dir, name))
+		prefixes = append(prefixes, strings.ToUpper(strings.TrimSuffix(name, filepath.Ext(name))))
+	}
+
+	r := newRangeReader(files, prefixes)
+	defer r.Close()
+
+	return breach.BuildIndex(r, out, kind)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// rangeReader streams range files as one HASH:COUNT dump, prepending each
+// file's prefix to its SUFFIX:COUNT lines.
+type rangeReader struct {
+	files    []string
+	prefixes []string
+	current  *os.File
+	scanner  *bufio.Scanner
+	prefix   string
+	buf      []byte
+}
+
+func newRangeReader(files, prefixes []string) *rangeReader {
+	return &rangeReader{files: files, prefixes: prefixes}
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.scanner != nil && r.scanner.Scan() {
+			line := strings.TrimSpace(r.scanner.Text())
+			if line != "" {
+				r.buf = []byte(r.prefix + line + "\n")
+			}
+			continue
+		}
+		if r.scanner != nil {
+			if err := r.scanner.Err(); err != nil {
+				return 0, err
+			}
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next opens the following range file, or returns io.EOF after the last one.
+func (r *rangeReader) next() error {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+	if len(r.files) == 0 {
+		return io.EOF
+	}
+
+	f, err := os.Open(r.files[0])
+	if err != nil {
+		return err
+	}
+	r.current = f
+	r.scanner = bufio.NewScanner(f)
+	r.prefix = r.prefixes[0]
+	r.files, r.prefixes = r.files[1:], r.prefixes[1:]
+
+	return nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
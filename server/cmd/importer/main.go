@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/password"
+	"github.com/LullNil/authx-go/internal/repository"
+	"github.com/LullNil/authx-go/internal/repository/postgres"
+)
+
+// record is one imported user. PasswordHash is stored as-is unless the source
+// needs it re-encoded (Firebase exports the hash and salt separately).
+type record struct {
+	Email        string `json:"email"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Salt         string `json:"salt"`
+}
+
+const (
+	sourceRaw      = "raw"
+	sourceFirebase = "firebase"
+)
+
+var (
+	emailRegexp       = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
+	usernameRegexp    = regexp.MustCompile(`^[a-z0-9_]{3,25}$`)
+	usernameSanitizer = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+func main() {
+	var filePath, format, source string
+	var dryRun bool
+
+	flag.StringVar(&filePath, "file", "", "path to the CSV or JSONL file to import")
+	flag.StringVar(&format, "format", "", "input format (csv, jsonl); defaults to the file extension")
+	flag.StringVar(&source, "source", sourceRaw, "hash source (raw: self-describing hashes such as Django or phpass, firebase: base64 hash + salt columns)")
+	flag.BoolVar(&dryRun, "dry-run", false, "validate the file without writing to the database")
+	flag.Parse()
+
+	if filePath == "" {
+		log.Fatal("file is required")
+	}
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(filePath), ".")
+	}
+	if source != sourceRaw && source != sourceFirebase {
+		log.Fatalf("Unknown source: %s. Use 'raw' or 'firebase'.", source)
+	}
+
+	cfg, err := config.New()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	passwords, err := password.New(cfg.Password)
+	if err != nil {
+		log.Fatalf("Failed to init password hasher: %v", err)
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		log.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+
+	var records <-chan result
+	switch format {
+	case "csv":
+		records = readCSV(f)
+	case "jsonl", "ndjson":
+		records = readJSONL(f)
+	default:
+		log.Fatalf("Unknown format: %s. Use 'csv' or 'jsonl'.", format)
+	}
+
+	var saver user.Saver
+	if !dryRun {
+		db, err := postgres.ConnectWithRetries(context.Background(), cfg.Postgres, slog.Default())
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		saver = postgres.NewUserRepository(db)
+	}
+
+	var imported, skipped, failed int
+	for res := range records {
+		if res.err != nil {
+			log.Printf("line %d: %v", res.line, res.err)
+			failed++
+			continue
+		}
+
+		u, err := toUser(res.rec, source, passwords)
+		if err != nil {
+			log.Printf("line %d: %v", res.line, err)
+			failed++
+			continue
+		}
+
+		if dryRun {
+			imported++
+			continue
+		}
+
+		if _, err := saver.Save(context.Background(), u); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				log.Printf("line %d: user %s already exists, skipping", res.line, u.Email)
+				skipped++
+				continue
+			}
+			log.Fatalf("line %d: failed to save user: %v", res.line, err)
+		}
+		imported++
+	}
+
+	fmt.Printf("Import finished: %d imported, %d skipped, %d failed\n", imported, skipped, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// toUser validates a record and converts it to a user ready to be saved.
+func toUser(rec record, source string, passwords *password.Manager) (*user.User, error) {
+	email := strings.ToLower(strings.TrimSpace(rec.Email))
+	if !emailRegexp.MatchString(email) {
+		return nil, fmt.Errorf("invalid email %q", rec.Email)
+	}
+
+	username := strings.ToLower(strings.TrimSpace(rec.Username))
+	if username == "" {
+		username = usernameFromEmail(email)
+	}
+	if !usernameRegexp.MatchString(username) {
+		return nil, fmt.Errorf("invalid username %q", username)
+	}
+
+	hash := strings.TrimSpace(rec.PasswordHash)
+	if source == sourceFirebase {
+		if hash == "" || rec.Salt == "" {
+			return nil, errors.New("firebase records need password_hash and salt")
+		}
+		hash = password.EncodeFirebaseScrypt(hash, strings.TrimSpace(rec.Salt))
+	}
+	if err := passwords.Check(hash); err != nil {
+		return nil, fmt.Errorf("unsupported or disabled password hash for %s: %w", email, err)
+	}
+
+	return &user.User{
+		Email:    email,
+		Username: username,
+		Password: hash,
+	}, nil
+}
+
+// usernameFromEmail derives a username from the local part of an email address.
+func usernameFromEmail(email string) string {
+	local, _, _ := strings.Cut(email, "@")
+	name := strings.Trim(usernameSanitizer.ReplaceAllString(local, "_"), "_")
+	if len(name) > 25 {
+		name = name[:25]
+	}
+	return name
+}
+
+type result struct {
+	line int
+	rec  record
+	err  error
+}
+
+// readCSV streams records from a CSV file with a header row naming the record fields.
+func readCSV(r io.Reader) <-chan result {
+	out := make(chan result)
+
+	go func() {
+		defer close(out)
+
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+
+		header, err := cr.Read()
+		if err != nil {
+			out <- result{line: 1, err: fmt.Errorf("read header: %w", err)}
+			return
+		}
+		cols := make(map[string]int, len(header))
+		for i, name := range header {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		field := func(row []string, name string) string {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+
+		for line := 2; ; line++ {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				out <- result{line: line, err: err}
+				continue
+			}
+			out <- result{line: line, rec: record{
+				Email:        field(row, "email"),
+				Username:     field(row, "username"),
+				PasswordHash: field(row, "password_hash"),
+				Salt:         field(row, "salt"),
+			}}
+		}
+	}()
+
+	return out
+}
+
+// readJSONL streams records from a file with one JSON object per line.
+func readJSONL(r io.Reader) <-chan result {
+	out := make(chan result)
+
+	go func() {
+		defer close(out)
+
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for line := 1; sc.Scan(); line++ {
+			text := strings.TrimSpace(sc.Text())
+			if text == "" {
+				continue
+			}
+			var rec record
+			if err := json.Unmarshal([]byte(text), &rec); err != nil {
+				out <- result{line: line, err: err}
+				continue
+			}
+			out <- result{line: line, rec: rec}
+		}
+		if err := sc.Err(); err != nil {
+			out <- result{err: err}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/internal/lib/jwt"
+)
+
+func main() {
+	var keyringPath, command, kid, alg string
+	var retention, jwksMaxAge time.Duration
+	var force bool
+
+	flag.StringVar(&keyringPath, "keyring", "", "path to the keyring file (e.g., './keys/keyring.yaml')")
+	flag.StringVar(&command, "command", "list", "keyring command (list, generate, promote, prune)")
+	flag.StringVar(&kid, "kid", "", "key id (generate: defaults to a timestamp, promote: required)")
+	flag.StringVar(&alg, "alg", jwt.AlgEdDSA, "algorithm of generated keys (RS256, EdDSA)")
+	flag.DurationVar(&retention, "retention", time.Hour, "how long retired keys are kept; must cover access token TTL plus leeway")
+	flag.DurationVar(&jwksMaxAge, "jwks-max-age", 5*time.Minute, "jwt.jwks_max_age of the server; staged keys are promoted only once they are this old")
+	flag.BoolVar(&force, "force", false, "promote a staged key before verifiers can have fetched it")
+	flag.Parse()
+
+	if keyringPath == "" {
+		log.Fatal("keyring is required")
+	}
+
+	kr, err := jwt.ReadKeyring(keyringPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || command != "generate" {
+			log.Fatalf("Failed to read keyring: %v", err)
+		}
+		kr = &jwt.Keyring{}
+	}
+
+	now := time.Now().UTC()
+
+	switch command {
+	case "list":
+		for _, k := range kr.Keys {
+			line := fmt.Sprintf("%-24s %-6s %-8s", k.ID, k.Algorithm, k.Status)
+			if k.RetiredAt != nil {
+				line += " retired_at=" + k.RetiredAt.Format(time.RFC3339)
+			}
+			fmt.Println(line)
+		}
+		return
+
+	case "generate":
+		if kid == "" {
+			kid = now.Format("20060102-150405")
+		}
+		if _, ok := kr.Find(kid); ok {
+			log.Fatalf("Key %q already exists", kid)
+		}
+
+		pemData, err := jwt.GeneratePrivateKey(alg)
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		file := kid + ".pem"
+		if err := os.MkdirAll(filepath.Dir(keyringPath), 0o700); err != nil {
+			log.Fatalf("Failed to create keyring directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(filepath.Dir(keyringPath), file), pemData, 0o600); err != nil {
+			log.Fatalf("Failed to write private key: %v", err)
+		}
+
+		// The very first key signs right away, later ones are staged so
+		// verifiers can pick them up from the JWKS before they are used.
+		status := jwt.KeyStatusStaged
+		if _, ok := kr.Active(); !ok {
+			status = jwt.KeyStatusActive
+		}
+
+		kr.Keys = append(kr.Keys, config.SigningKey{
+			ID:             kid,
+			Algorithm:      alg,
+			Status:         status,
+			CreatedAt:      &now,
+			PrivateKeyPath: file,
+		})
+		fmt.Printf("Key %s generated (%s)\n", kid, status)
+
+	case "promote":
+		next, ok := kr.Find(kid)
+		if !ok {
+			log.Fatalf("Key %q not found", kid)
+		}
+		if next.Status != jwt.KeyStatusStaged {
+			log.Fatalf("Key %q is %s, only staged keys can be promoted", kid, next.Status)
+		}
+		// Verifiers cache the JWKS, so tokens signed with a key published
+		// less than a max-age ago would be rejected by some of them.
+		if !force && (next.CreatedAt == nil || now.Sub(*next.CreatedAt) < jwksMaxAge) {
+			log.Fatalf("Key %q may not be in cached JWKS documents yet; wait %s after generating it or pass -force", kid, jwksMaxAge)
+		}
+		if current, ok := kr.Active(); ok {
+			current.Status = jwt.KeyStatusRetired
+			current.RetiredAt = &now
+			fmt.Printf("Key %s retired\n", current.ID)
+		}
+		next.Status = jwt.KeyStatusActive
+		fmt.Printf("Key %s promoted to active\n", kid)
+
+	case "prune":
+		kept := kr.Keys[:0]
+		for _, k := range kr.Keys {
+			if k.Status == jwt.KeyStatusRetired && k.RetiredAt != nil && now.Sub(*k.RetiredAt) > retention {
+				if k.PrivateKeyPath != "" && !filepath.IsAbs(k.PrivateKeyPath) {
+					_ = os.Remove(filepath.Join(filepath.Dir(keyringPath), k.PrivateKeyPath))
+				}
+				fmt.Printf("Key %s pruned\n", k.ID)
+				continue
+			}
+			kept = append(kept, k)
+		}
+		kr.Keys = kept
+
+	default:
+		log.Fatalf("Unknown command: %s. Use 'list', 'generate', 'promote' or 'prune'.", command)
+	}
+
+	if err := jwt.WriteKeyring(keyringPath, kr); err != nil {
+		log.Fatalf("Failed to write keyring: %v", err)
+	}
+}
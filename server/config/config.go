@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"time"
 
@@ -10,15 +13,34 @@ import (
 )
 
 type Config struct {
-	Env        string     `yaml:"env"`
-	HTTPServer HTTPServer `yaml:"http_server"`
-	Postgres   Postgres   `yaml:"postgres"`
+	Env               string            `yaml:"env"`
+	HTTPServer        HTTPServer        `yaml:"http_server"`
+	Postgres          Postgres          `yaml:"postgres"`
+	JWT               JWT               `yaml:"jwt"`
+	RefreshToken      RefreshToken      `yaml:"refresh_token"`
+	Session           Session           `yaml:"session"`
+	Revocation        Revocation        `yaml:"revocation"`
+	Cookie            Cookie            `yaml:"cookie"`
+	Password          Password          `yaml:"password"`
+	PasswordReset     PasswordReset     `yaml:"password_reset"`
+	Mail              Mail              `yaml:"mail"`
+	SMS               SMS               `yaml:"sms"`
+	EmailVerification EmailVerification `yaml:"email_verification"`
+	EmailChange       EmailChange       `yaml:"email_change"`
+	MagicLink         MagicLink         `yaml:"magic_link"`
+	MFA               MFA               `yaml:"mfa"`
+	WebAuthn          WebAuthn          `yaml:"webauthn"`
+	RateLimit         RateLimit         `yaml:"rate_limit"`
 }
 
 type HTTPServer struct {
 	Port         string        `yaml:"port"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// TrustedProxies are the CIDRs of the reverse proxies whose X-Forwarded-For
+	// and X-Real-IP headers are believed. Other peers are identified by their
+	// own address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type Postgres struct {
@@ -28,11 +50,370 @@ type Postgres struct {
 	ConnectTimeout time.Duration `yaml:"connect_timeout" env-default:"30s"`
 }
 
+type JWT struct {
+	Issuer         string        `yaml:"issuer" env-default:"authx"`
+	Audience       []string      `yaml:"audience"`
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" env-default:"15m"`
+	Leeway         time.Duration `yaml:"leeway" env-default:"30s"`
+
+	// Keys lists the signing keys inline. Exactly one of them must be active.
+	Keys []SigningKey `yaml:"keys"`
+	// KeyringPath points to a YAML file with a "keys" list managed by the keys CLI.
+	// It is reloaded every KeyringReloadInterval so rotations apply without a restart.
+	KeyringPath           string        `yaml:"keyring_path" env:"JWT_KEYRING_PATH"`
+	KeyringReloadInterval time.Duration `yaml:"keyring_reload_interval" env-default:"1m"`
+	// JWKSMaxAge is the Cache-Control max-age of the JWKS document.
+	JWKSMaxAge time.Duration `yaml:"jwks_max_age" env-default:"5m"`
+
+	// Single key settings, used only when neither Keys nor KeyringPath is set.
+	Algorithm string `yaml:"algorithm" env-default:"HS256"`
+	KeyID     string `yaml:"key_id" env:"JWT_KEY_ID"`
+
+	// HS256 shared secret. Prefer JWT_SECRET over storing it in the YAML file.
+	Secret string `yaml:"secret" env:"JWT_SECRET"`
+
+	// RS256/EdDSA private key, either PEM-encoded inline (env) or a path to a PEM file.
+	PrivateKey     string `yaml:"private_key" env:"JWT_PRIVATE_KEY"`
+	PrivateKeyPath string `yaml:"private_key_path" env:"JWT_PRIVATE_KEY_PATH"`
+}
+
+// SigningKey describes one key of the JWT keyring.
+type SigningKey struct {
+	ID        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm"`
+	// Status is one of "staged" (published, not signing), "active" (signing)
+	// or "retired" (verification only until tokens it signed have expired).
+	Status    string     `yaml:"status"`
+	CreatedAt *time.Time `yaml:"created_at,omitempty"`
+	RetiredAt *time.Time `yaml:"retired_at,omitempty"`
+
+	Secret         string `yaml:"secret,omitempty"`
+	PrivateKey     string `yaml:"private_key,omitempty"`
+	PrivateKeyPath string `yaml:"private_key_path,omitempty"`
+}
+
+// RefreshToken configures refresh token rotation. How long tokens live is
+// set by the session policies.
+type RefreshToken struct {
+	// Sliding extends the expiry by the idle timeout on every rotation, up to
+	// the absolute lifetime of the session. When disabled, rotated tokens keep
+	// the expiry of the token they replace.
+	Sliding bool `yaml:"sliding"`
+
+	// Deprecated: replaced by session.*.idle_timeout and
+	// session.*.absolute_lifetime. They are only read to refuse configs that
+	// still set them instead of silently ignoring the values.
+	TTL              time.Duration `yaml:"ttl"`
+	AbsoluteLifetime time.Duration `yaml:"absolute_lifetime"`
+}
+
+// Session configures how long sessions last. Set them per environment in its
+// config file; e.g. production usually wants shorter timeouts than local.
+type Session struct {
+	// Default applies to logins without remember_me.
+	Default SessionPolicy `yaml:"default"`
+	// RememberMe applies to logins with remember_me set.
+	RememberMe SessionPolicy `yaml:"remember_me"`
+	// ActivityFlushInterval is how often the last activity of sessions is
+	// written to the database. In between it is only kept in memory.
+	ActivityFlushInterval time.Duration `yaml:"activity_flush_interval" env-default:"1m"`
+}
+
+// SessionPolicy bounds the lifetime of a session.
+type SessionPolicy struct {
+	// IdleTimeout ends a session that has not been used for this long.
+	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"168h"`
+	// AbsoluteLifetime ends a session this long after login, however active it is.
+	AbsoluteLifetime time.Duration `yaml:"absolute_lifetime" env-default:"720h"`
+}
+
+// Policy returns the policy of a session started with or without remember_me.
+func (s Session) Policy(rememberMe bool) SessionPolicy {
+	if rememberMe {
+		return s.RememberMe
+	}
+	return s.Default
+}
+
+type PasswordReset struct {
+	// TokenTTL is how long a reset link stays valid.
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"30m"`
+	// URL is the page that handles the reset; the token is appended as the "token" query parameter.
+	URL string `yaml:"url" env-default:"http://localhost:3000/reset-password"`
+}
+
+type EmailVerification struct {
+	// Mode is "flag" (unverified users can log in and their access tokens carry
+	// email_verified=false) or "block" (login is refused until the email is verified).
+	Mode string `yaml:"mode" env-default:"flag"`
+	// Secret signs verification links. Changing it invalidates links already sent.
+	Secret   string        `yaml:"secret" env:"EMAIL_VERIFICATION_SECRET"`
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"48h"`
+	// URL is the page that handles the link; the token is appended as the "token" query parameter.
+	URL string `yaml:"url" env-default:"http://localhost:3000/verify-email"`
+	// ResendInterval is the minimum time between verification emails to the same address.
+	ResendInterval time.Duration `yaml:"resend_interval" env-default:"1m"`
+}
+
+type EmailChange struct {
+	// ConfirmTTL is how long the confirmation link sent to the new address stays valid.
+	ConfirmTTL time.Duration `yaml:"confirm_ttl" env-default:"24h"`
+	// RevertTTL is how long the link sent to the old address can cancel or undo the change.
+	RevertTTL  time.Duration `yaml:"revert_ttl" env-default:"168h"`
+	ConfirmURL string        `yaml:"confirm_url" env-default:"http://localhost:3000/confirm-email"`
+	RevertURL  string        `yaml:"revert_url" env-default:"http://localhost:3000/revert-email"`
+}
+
+// MagicLink configures passwordless login by emailed one-time links.
+type MagicLink struct {
+	Enabled  bool          `yaml:"enabled" env-default:"false"`
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"15m"`
+	// URL is the callback the link points to; the token is appended as the "token" query parameter.
+	URL string `yaml:"url" env-default:"http://localhost:8085/user/login/magic/callback"`
+	// NonceCookie binds a link to the browser that requested it.
+	NonceCookie string `yaml:"nonce_cookie" env-default:"magic_nonce"`
+	NoncePath   string `yaml:"nonce_path" env-default:"/user/login/magic"`
+}
+
+type MFA struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string `yaml:"issuer" env-default:"AuthX"`
+	// EncryptionKey is a base64-encoded 32 byte key that encrypts TOTP secrets
+	// at rest and keys the hashes of recovery, email and SMS codes.
+	EncryptionKey string `yaml:"encryption_key" env:"MFA_ENCRYPTION_KEY"`
+	// TOTPSkew is the number of 30 second steps accepted before and after the current one.
+	TOTPSkew int `yaml:"totp_skew"`
+	// RecoveryCodes is the number of recovery codes generated at a time.
+	RecoveryCodes int `yaml:"recovery_codes" env-default:"10"`
+	// ChallengeTTL is how long a login has to complete its second step.
+	ChallengeTTL time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	// MaxAttempts is the number of codes that can be tried per challenge, and
+	// the number of wrong TOTP codes in a row before TOTP is locked.
+	MaxAttempts int `yaml:"max_attempts" env-default:"5"`
+	// TOTPLockout is how long TOTP codes are refused after MaxAttempts wrong ones.
+	TOTPLockout time.Duration `yaml:"totp_lockout" env-default:"15m"`
+	// StepUpMaxAge is how recent a multi-factor authentication must be for
+	// routes that require step-up.
+	StepUpMaxAge time.Duration `yaml:"step_up_max_age" env-default:"5m"`
+	EmailOTP     EmailOTP      `yaml:"email_otp"`
+	SMSOTP       SMSOTP        `yaml:"sms_otp"`
+}
+
+// EmailOTP configures one-time codes sent by email.
+type EmailOTP struct {
+	TTL time.Duration `yaml:"ttl" env-default:"10m"`
+	// MaxAttempts is the number of guesses allowed per code.
+	MaxAttempts int `yaml:"max_attempts" env-default:"5"`
+	// ResendInterval is the minimum time between two codes for the same purpose.
+	ResendInterval time.Duration `yaml:"resend_interval" env-default:"1m"`
+}
+
+// SMSOTP configures one-time codes sent by SMS. Every message costs money,
+// so sends are throttled per number and per client IP, and only numbers from
+// AllowedCountryCodes are accepted.
+type SMSOTP struct {
+	TTL         time.Duration `yaml:"ttl" env-default:"5m"`
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	// AllowedCountryCodes are the calling codes numbers may have, e.g. ["1", "44"].
+	// An empty list rejects every number.
+	AllowedCountryCodes []string `yaml:"allowed_country_codes"`
+	PerNumber           Throttle `yaml:"per_number"`
+	PerIP               Throttle `yaml:"per_ip"`
+}
+
+// Throttle allows Requests per Window.
+type Throttle struct {
+	Requests int           `yaml:"requests" env-default:"5"`
+	Window   time.Duration `yaml:"window" env-default:"1h"`
+}
+
+// SMS configures outgoing text messages.
+type SMS struct {
+	// Transport is one of "log" (write messages to the log), "file" (append
+	// them to File.Path) or "twilio" (any Twilio-compatible HTTP API).
+	Transport string  `yaml:"transport" env-default:"log"`
+	From      string  `yaml:"from"`
+	Twilio    Twilio  `yaml:"twilio"`
+	File      SMSFile `yaml:"file"`
+}
+
+type Twilio struct {
+	BaseURL    string        `yaml:"base_url" env-default:"https://api.twilio.com"`
+	AccountSID string        `yaml:"account_sid" env:"TWILIO_ACCOUNT_SID"`
+	AuthToken  string        `yaml:"auth_token" env:"TWILIO_AUTH_TOKEN"`
+	Timeout    time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type SMSFile struct {
+	Path string `yaml:"path" env-default:"./mail/sms.log"`
+}
+
+// WebAuthn configures the relying party for passkeys.
+type WebAuthn struct {
+	// RPID is the domain passkeys are bound to, e.g. "example.com".
+	RPID          string `yaml:"rp_id" env-default:"localhost"`
+	RPDisplayName string `yaml:"rp_display_name" env-default:"AuthX"`
+	// RPOrigins are the origins of the pages allowed to run the ceremonies.
+	RPOrigins []string `yaml:"rp_origins"`
+	// Timeout is how long a registration or login ceremony can take.
+	Timeout time.Duration `yaml:"timeout" env-default:"5m"`
+}
+
+// RateLimit limits requests per client IP on unauthenticated endpoints that send email.
+type RateLimit struct {
+	Requests  int           `yaml:"requests" env-default:"10"`
+	Window    time.Duration `yaml:"window" env-default:"1m"`
+	CacheSize int           `yaml:"cache_size" env-default:"10000"`
+}
+
+type Mail struct {
+	// Transport is one of "log" (write messages to the log), "file" (write .eml
+	// files to File.Dir) or "smtp".
+	Transport string `yaml:"transport" env-default:"log"`
+	From      string `yaml:"from" env-default:"AuthX <no-reply@localhost>"`
+	// TemplatesDir holds <locale>/<name>.txt and <locale>/<name>.html files
+	// that override the built-in templates of the same name.
+	TemplatesDir  string   `yaml:"templates_dir"`
+	DefaultLocale string   `yaml:"default_locale" env-default:"en"`
+	SMTP          SMTP     `yaml:"smtp"`
+	File          MailFile `yaml:"file"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env-default:"587"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	// TLS is one of "starttls", "implicit" (SMTPS, usually port 465) or "none".
+	TLS     string        `yaml:"tls" env-default:"starttls"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type MailFile struct {
+	Dir string `yaml:"dir" env-default:"./mail"`
+}
+
+type Revocation struct {
+	// CacheSize is the number of denylist entries kept in memory.
+	CacheSize int `yaml:"cache_size" env-default:"10000"`
+	// NegativeCacheTTL is how long a "not revoked" answer is cached.
+	NegativeCacheTTL time.Duration `yaml:"negative_cache_ttl" env-default:"5s"`
+	// CleanupInterval is how often expired denylist entries are purged from the database.
+	CleanupInterval time.Duration `yaml:"cleanup_interval" env-default:"1h"`
+}
+
+type Cookie struct {
+	// Transport selects how tokens are delivered: "bearer" (response body only),
+	// "cookie" (HttpOnly cookies only) or "both".
+	Transport string `yaml:"transport" env-default:"bearer"`
+	Domain    string `yaml:"domain"`
+	Path      string `yaml:"path" env-default:"/"`
+	// RefreshPath limits the refresh token cookie to the routes that consume it.
+	RefreshPath string `yaml:"refresh_path" env-default:"/user"`
+	// Secure defaults to true; see defaults.
+	Secure bool `yaml:"secure"`
+	// SameSite is one of "lax", "strict" or "none".
+	SameSite    string `yaml:"same_site" env-default:"lax"`
+	AccessName  string `yaml:"access_name" env-default:"access_token"`
+	RefreshName string `yaml:"refresh_name" env-default:"refresh_token"`
+	CSRFName    string `yaml:"csrf_name" env-default:"csrf_token"`
+	CSRFHeader  string `yaml:"csrf_header" env-default:"X-CSRF-Token"`
+}
+
+type Password struct {
+	// Algorithm used for new hashes: "argon2id" or "bcrypt". Hashes made with
+	// the other algorithm or outdated parameters are upgraded on login.
+	Algorithm string         `yaml:"algorithm" env-default:"argon2id"`
+	Argon2id  Argon2id       `yaml:"argon2id"`
+	Bcrypt    Bcrypt         `yaml:"bcrypt"`
+	Legacy    LegacyPassword `yaml:"legacy"`
+	Pepper    Pepper         `yaml:"pepper"`
+	Policy    PasswordPolicy `yaml:"policy"`
+	Breach    BreachCheck    `yaml:"breach"`
+	History   History        `yaml:"history"`
+}
+
+type Argon2id struct {
+	Memory      uint32 `yaml:"memory" env-default:"65536"` // KiB
+	Iterations  uint32 `yaml:"iterations" env-default:"3"`
+	Parallelism uint32 `yaml:"parallelism" env-default:"2"`
+	SaltLength  uint32 `yaml:"salt_length" env-default:"16"`
+	KeyLength   uint32 `yaml:"key_length" env-default:"32"`
+}
+
+type Bcrypt struct {
+	Cost int `yaml:"cost" env-default:"12"`
+}
+
+type PasswordPolicy struct {
+	MinLength int `yaml:"min_length" env-default:"8"`
+	MaxLength int `yaml:"max_length" env-default:"128"`
+
+	RequireUpper   bool `yaml:"require_upper"`
+	RequireLower   bool `yaml:"require_lower"`
+	RequireDigit   bool `yaml:"require_digit"`
+	RequireSymbol  bool `yaml:"require_symbol"`
+	MinCharClasses int  `yaml:"min_char_classes"`
+
+	// DisallowUserInfo rejects passwords containing the username or email.
+	DisallowUserInfo bool `yaml:"disallow_user_info"`
+	// MinScore is the minimum zxcvbn strength score (0-4); 0 disables the check.
+	MinScore int `yaml:"min_score"`
+}
+
+// BreachCheck rejects passwords found in a local Have I Been Pwned index built with the hibp CLI.
+type BreachCheck struct {
+	// IndexPath is the index file; empty disables the check.
+	IndexPath string `yaml:"index_path" env:"PASSWORD_BREACH_INDEX_PATH"`
+	// Mode is "reject" (the password fails validation) or "warn" (it is only logged).
+	Mode string `yaml:"mode" env-default:"reject"`
+	// MinCount is how many times a password must appear in breaches to be flagged.
+	MinCount int `yaml:"min_count" env-default:"1"`
+}
+
+// History prevents reusing recent passwords.
+type History struct {
+	// Size is how many recent passwords are remembered; 0 disables the check.
+	Size int `yaml:"size"`
+	// Retention forgets passwords older than this; 0 keeps them until pushed out by newer ones.
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Pepper is a secret HMAC key mixed into passwords before hashing. It must
+// be kept outside the database. Version 0 disables peppering; bump the
+// version and move the old key to Previous to rotate it.
+type Pepper struct {
+	Version    int         `yaml:"version" env:"PASSWORD_PEPPER_VERSION"`
+	Secret     string      `yaml:"secret" env:"PASSWORD_PEPPER"`
+	SecretPath string      `yaml:"secret_path" env:"PASSWORD_PEPPER_PATH"`
+	Previous   []PepperKey `yaml:"previous"`
+}
+
+type PepperKey struct {
+	Version    int    `yaml:"version"`
+	Secret     string `yaml:"secret"`
+	SecretPath string `yaml:"secret_path"`
+}
+
+// LegacyPassword enables verification of hashes imported from other identity
+// systems. They are replaced with the current algorithm on first login.
+type LegacyPassword struct {
+	Enabled        bool           `yaml:"enabled"`
+	FirebaseScrypt FirebaseScrypt `yaml:"firebase_scrypt"`
+}
+
+// FirebaseScrypt holds the project-wide hash parameters shown in the
+// Firebase console (Authentication > Users > Password hash parameters).
+type FirebaseScrypt struct {
+	SignerKey     string `yaml:"signer_key" env:"FIREBASE_SCRYPT_SIGNER_KEY"` // base64
+	SaltSeparator string `yaml:"salt_separator"`                              // base64
+	Rounds        int    `yaml:"rounds" env-default:"8"`
+	MemCost       int    `yaml:"mem_cost" env-default:"14"`
+}
+
 func New() (*Config, error) {
 	_ = godotenv.Load()
 
-	var cfg Config
-
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
 		configPath = "config/local.yaml"
@@ -43,13 +424,103 @@ func New() (*Config, error) {
 		log.Fatalf("config file not found: %s", configPath)
 	}
 
-	// Read config from YAML
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("cannot read config: %s", err)
+	cfg, err := load(configPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Log config
 	log.Printf("loaded config from %s\n", configPath)
 
+	return cfg, nil
+}
+
+// load reads and validates the config file at path.
+func load(path string) (*Config, error) {
+	cfg := defaults()
+
+	// Read config from YAML
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot read config %s: %w", path, err)
+	}
+
+	// Validate config
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
 	return &cfg, nil
 }
+
+// defaults returns the defaults of settings whose zero value is meaningful.
+// cleanenv applies env-default to every field still zero after the file is
+// read, so a "false" or 0 from YAML would be replaced; these are set before
+// reading instead and only overridden by keys present in the file.
+func defaults() Config {
+	var cfg Config
+	cfg.RefreshToken.Sliding = true
+	cfg.Cookie.Secure = true
+	cfg.Password.Policy.DisallowUserInfo = true
+	cfg.Password.Policy.MinScore = 2
+	cfg.Password.History.Size = 5
+	cfg.Password.Legacy.Enabled = true
+	cfg.MFA.TOTPSkew = 1
+	cfg.Password.History.Retention = 8760 * time.Hour
+	return cfg
+}
+
+// validate rejects values that would otherwise fail later at runtime or
+// silently weaken security.
+func (c *Config) validate() error {
+	var errs []error
+
+	if c.Revocation.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("revocation.cleanup_interval must be positive"))
+	}
+	if c.MFA.TOTPLockout <= 0 {
+		errs = append(errs, errors.New("mfa.totp_lockout must be positive"))
+	}
+	if c.RefreshToken.TTL != 0 || c.RefreshToken.AbsoluteLifetime != 0 {
+		errs = append(errs, errors.New("refresh_token.ttl and refresh_token.absolute_lifetime were replaced by session.default and session.remember_me"))
+	}
+	for _, p := range []struct {
+		name   string
+		policy SessionPolicy
+	}{
+		{"default", c.Session.Default},
+		{"remember_me", c.Session.RememberMe},
+	} {
+		if p.policy.IdleTimeout <= 0 {
+			errs = append(errs, fmt.Errorf("session.%s.idle_timeout must be positive", p.name))
+		}
+		if p.policy.IdleTimeout > p.policy.AbsoluteLifetime {
+			errs = append(errs, fmt.Errorf("session.%s.idle_timeout must not exceed absolute_lifetime", p.name))
+		}
+		// Otherwise access tokens would keep working after their session idled out.
+		if p.policy.IdleTimeout < c.JWT.AccessTokenTTL {
+			errs = append(errs, fmt.Errorf("session.%s.idle_timeout must not be shorter than jwt.access_token_ttl", p.name))
+		}
+	}
+	if c.Session.ActivityFlushInterval <= 0 {
+		errs = append(errs, errors.New("session.activity_flush_interval must be positive"))
+	}
+	// A typo such as "blocked" would silently fall back to "flag".
+	if m := c.EmailVerification.Mode; m != "flag" && m != "block" {
+		errs = append(errs, fmt.Errorf(`email_verification.mode must be "flag" or "block", got %q`, m))
+	}
+	for _, cidr := range c.HTTPServer.TrustedProxies {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("http_server.trusted_proxies: %w", err))
+		}
+	}
+	// The log transports write reset and verification links and one-time
+	// codes to the application log.
+	if c.Env != "local" && c.Mail.Transport == "log" {
+		errs = append(errs, errors.New(`mail.transport "log" is only allowed with env "local"`))
+	}
+	if c.Env != "local" && c.SMS.Transport == "log" {
+		errs = append(errs, errors.New(`sms.transport "log" is only allowed with env "local"`))
+	}
+
+	return errors.Join(errs...)
+}
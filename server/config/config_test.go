@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadYAML(t *testing.T, yaml string) *Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("env: \"local\"\n"+yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	return cfg
+}
+
+func TestDefaults(t *testing.T) {
+	cfg := loadYAML(t, "")
+
+	if !cfg.RefreshToken.Sliding {
+		t.Error("refresh_token.sliding defaults to false, want true")
+	}
+	if !cfg.Cookie.Secure {
+		t.Error("cookie.secure defaults to false, want true")
+	}
+	if !cfg.Password.Policy.DisallowUserInfo {
+		t.Error("password.policy.disallow_user_info defaults to false, want true")
+	}
+	if cfg.Password.Policy.MinScore != 2 {
+		t.Errorf("password.policy.min_score defaults to %d, want 2", cfg.Password.Policy.MinScore)
+	}
+	if cfg.Password.History.Size != 5 || cfg.Password.History.Retention != 8760*time.Hour {
+		t.Errorf("password.history defaults to %+v, want size 5 and retention 8760h", cfg.Password.History)
+	}
+	if !cfg.Password.Legacy.Enabled {
+		t.Error("password.legacy.enabled defaults to false, want true")
+	}
+	if cfg.MFA.TOTPSkew != 1 {
+		t.Errorf("mfa.totp_skew defaults to %d, want 1", cfg.MFA.TOTPSkew)
+	}
+}
+
+func TestExplicitZeroValuesAreKept(t *testing.T) {
+	cfg := loadYAML(t, `
+refresh_token:
+  sliding: false
+cookie:
+  secure: false
+password:
+  policy:
+    disallow_user_info: false
+    min_score: 0
+  history:
+    size: 0
+    retention: 0s
+  legacy:
+    enabled: false
+mfa:
+  totp_skew: 0
+`)
+
+	if cfg.RefreshToken.Sliding {
+		t.Error("refresh_token.sliding: false was overridden by the default")
+	}
+	if cfg.Cookie.Secure {
+		t.Error("cookie.secure: false was overridden by the default")
+	}
+	if cfg.Password.Policy.DisallowUserInfo {
+		t.Error("password.policy.disallow_user_info: false was overridden by the default")
+	}
+	if cfg.Password.Policy.MinScore != 0 {
+		t.Errorf("password.policy.min_score: 0 was overridden with %d", cfg.Password.Policy.MinScore)
+	}
+	if cfg.Password.History.Size != 0 || cfg.Password.History.Retention != 0 {
+		t.Errorf("password.history zero values were overridden with %+v", cfg.Password.History)
+	}
+	if cfg.Password.Legacy.Enabled {
+		t.Error("password.legacy.enabled: false was overridden by the default")
+	}
+	if cfg.MFA.TOTPSkew != 0 {
+		t.Errorf("mfa.totp_skew: 0 was overridden with %d", cfg.MFA.TOTPSkew)
+	}
+}
+
+func TestIdleTimeoutShorterThanAccessTokenIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+env: "local"
+jwt:
+  access_token_ttl: 15m
+session:
+  default:
+    idle_timeout: 10m
+    absolute_lifetime: 24h
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := load(path); err == nil {
+		t.Error("load accepted an idle_timeout shorter than access_token_ttl")
+	}
+}
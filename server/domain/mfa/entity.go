@@ -0,0 +1,99 @@
+package mfa
+
+import "time"
+
+// Second factor methods.
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+	MethodWebAuthn     = "webauthn"
+	MethodEmailOTP     = "email_otp"
+	MethodSMSOTP       = "sms_otp"
+)
+
+// MethodAMR returns the RFC 8176 amr value of a second factor method.
+func MethodAMR(method string) string {
+	switch method {
+	case MethodWebAuthn:
+		return "hwk"
+	case MethodSMSOTP:
+		return "sms"
+	default:
+		return "otp"
+	}
+}
+
+// Independent reports whether method proves something other than
+// firstFactor, the amr value a login started with. An email code after a
+// magic link only proves control of the same mailbox again.
+func Independent(firstFactor, method string) bool {
+	return !(firstFactor == "email" && method == MethodEmailOTP)
+}
+
+// IndependentMethods returns the methods that can complete a login started
+// with firstFactor. Recovery codes are kept, so users whose only factor was
+// left out can still finish the login with one.
+func IndependentMethods(firstFactor string, methods []string) []string {
+	var independent []string
+	for _, m := range methods {
+		if Independent(firstFactor, m) {
+			independent = append(independent, m)
+		}
+	}
+	return independent
+}
+
+// Channels one-time codes are delivered through.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Purposes a one-time code can be sent for. A code only works for its purpose.
+const (
+	PurposeSetup  = "setup"
+	PurposeLogin  = "login"
+	PurposeStepUp = "step_up"
+)
+
+// TOTP is a user's authenticator app enrolment. Secret is encrypted at rest.
+type TOTP struct {
+	UserID      int64
+	Secret      []byte
+	CreatedAt   time.Time
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code, used to reject replays.
+	LastUsedStep int64
+	// Attempts counts codes tried since the last accepted one.
+	Attempts int
+	// LockedUntil is set when too many wrong codes were tried in a row.
+	LockedUntil *time.Time
+}
+
+// Challenge is a pending second step of a login. Only the hash of its token is stored.
+type Challenge struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	// FirstFactor is the method that started the login, e.g. "pwd".
+	FirstFactor string
+	Attempts    int
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	UsedAt      *time.Time
+	// RememberMe carries the remember_me flag of the login to its session.
+	RememberMe bool
+}
+
+// OneTimeCode is a code sent by email or SMS. Only its hash is stored.
+type OneTimeCode struct {
+	ID        int64
+	UserID    int64
+	Channel   string
+	Purpose   string
+	CodeHash  string
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,63 @@
+package mfa
+
+import (
+	"context"
+	"time"
+)
+
+type TOTPRepository interface {
+	// Save stores a new, unconfirmed enrolment, replacing any unconfirmed one of the user.
+	// It returns repository.ErrConflict if the user already has a confirmed enrolment.
+	Save(ctx context.Context, t *TOTP) error
+	GetByUser(ctx context.Context, userID int64) (*TOTP, error)
+	Confirm(ctx context.Context, userID int64, at time.Time) error
+	// UseStep records step as the last accepted one and resets the attempt counter.
+	// It returns repository.ErrConflict if step is not after the last accepted step.
+	UseStep(ctx context.Context, userID int64, step int64) error
+	// AddAttempt increments the attempt counter and returns the new count.
+	AddAttempt(ctx context.Context, userID int64) (int, error)
+	// Lock refuses codes until the given time and resets the attempt counter.
+	Lock(ctx context.Context, userID int64, until time.Time) error
+	Delete(ctx context.Context, userID int64) error
+}
+
+type RecoveryCodeRepository interface {
+	// Replace deletes the user's codes and stores the given code hashes.
+	Replace(ctx context.Context, userID int64, hashes []string, at time.Time) error
+	// Use consumes an unused code. It returns repository.ErrNotFound if there is none.
+	Use(ctx context.Context, userID int64, hash string, at time.Time) error
+	CountUnused(ctx context.Context, userID int64) (int, error)
+	DeleteByUser(ctx context.Context, userID int64) error
+}
+
+type ChallengeRepository interface {
+	Save(ctx context.Context, c *Challenge) (int64, error)
+	GetByHash(ctx context.Context, hash string) (*Challenge, error)
+	// AddAttempt increments the attempt counter and returns the new count.
+	AddAttempt(ctx context.Context, id int64) (int, error)
+	// MarkUsed consumes the challenge. It returns repository.ErrConflict if it was already used.
+	MarkUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+type EmailOTPRepository interface {
+	Enable(ctx context.Context, userID int64, at time.Time) error
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	Disable(ctx context.Context, userID int64) error
+}
+
+type SMSOTPRepository interface {
+	Enable(ctx context.Context, userID int64, at time.Time) error
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	Disable(ctx context.Context, userID int64) error
+}
+
+type OneTimeCodeRepository interface {
+	// Save stores a new code, replacing the user's previous code for the same channel and purpose.
+	Save(ctx context.Context, c *OneTimeCode) error
+	// GetLatest returns the user's current code for the channel and purpose.
+	GetLatest(ctx context.Context, userID int64, channel, purpose string) (*OneTimeCode, error)
+	// AddAttempt increments the attempt counter and returns the new count.
+	AddAttempt(ctx context.Context, id int64) (int, error)
+	// MarkUsed consumes the code. It returns repository.ErrConflict if it was already used.
+	MarkUsed(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,142 @@
+package mfa
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/session"
+)
+
+type Service interface {
+	// SetupTOTP starts an authenticator app enrolment. It takes effect once confirmed.
+	SetupTOTP(ctx context.Context, userID int64, account string) (*TOTPSetup, error)
+	// ConfirmTOTP enables TOTP with a first valid code and returns new recovery codes.
+	ConfirmTOTP(ctx context.Context, userID int64, code string) (*RecoveryCodes, error)
+	// DisableTOTP removes TOTP and the recovery codes after checking a current code.
+	DisableTOTP(ctx context.Context, userID int64, code string) error
+	// SetupEmailOTP emails a code that enables email OTP once confirmed.
+	SetupEmailOTP(ctx context.Context, userID int64, locale string) error
+	ConfirmEmailOTP(ctx context.Context, userID int64, code string) error
+	// DisableEmailOTP turns email OTP off. It requires step-up if other factors remain.
+	DisableEmailOTP(ctx context.Context, userID int64, stepUp *StepUp) error
+	// SetPhone stores a pending phone number and texts it a verification code.
+	// Replacing a number requires step-up if the user has a second factor.
+	SetPhone(ctx context.Context, req SetPhoneRequest) error
+	// VerifyPhone makes the pending number the user's phone with the code sent by SetPhone.
+	VerifyPhone(ctx context.Context, userID int64, code string) error
+	// EnableSMSOTP turns SMS OTP on for a user with a verified phone number.
+	EnableSMSOTP(ctx context.Context, userID int64) error
+	// DisableSMSOTP turns SMS OTP off. It requires step-up if other factors remain.
+	DisableSMSOTP(ctx context.Context, userID int64, stepUp *StepUp) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64) (*RecoveryCodes, error)
+	RecoveryCodesRemaining(ctx context.Context, userID int64) (int, error)
+
+	// Methods returns the second factors the user can complete a login with.
+	Methods(ctx context.Context, userID int64) ([]string, error)
+	// Verify checks a code of one of the user's second factors.
+	Verify(ctx context.Context, userID int64, method, code string) error
+
+	// StartChallenge creates the second step of a login that passed firstFactor.
+	StartChallenge(ctx context.Context, userID int64, firstFactor string, rememberMe bool, methods []string) (*ChallengeResponse, error)
+	// BeginWebAuthn starts a passkey ceremony for completing the challenge with MethodWebAuthn.
+	BeginWebAuthn(ctx context.Context, req ChallengeTokenRequest) (*passkey.Options, error)
+	// SendLoginCode emails a code for completing the challenge with MethodEmailOTP.
+	SendLoginCode(ctx context.Context, req ChallengeTokenRequest) error
+	// SendLoginSMS texts a code for completing the challenge with MethodSMSOTP.
+	SendLoginSMS(ctx context.Context, req ChallengeTokenRequest) error
+	// CompleteChallenge verifies a code for the challenge and returns its user.
+	// A challenge can be completed once and allows a limited number of attempts.
+	CompleteChallenge(ctx context.Context, req CompleteChallengeRequest) (*Challenge, error)
+
+	// BeginStepUp prepares a step-up with a method that needs it: it sends a
+	// code for MethodEmailOTP and MethodSMSOTP and starts a ceremony for MethodWebAuthn.
+	BeginStepUp(ctx context.Context, req BeginStepUpRequest) (*passkey.Options, error)
+	// RequireStepUp checks a second factor before a sensitive operation.
+	// Users without a second factor pass; for others a missing or invalid
+	// stepUp fails with the methods they can use.
+	RequireStepUp(ctx context.Context, userID int64, stepUp *StepUp) error
+}
+
+type TOTPSetup struct {
+	// Secret is the base32 secret for manual entry.
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	// QRCode is a PNG of URI.
+	QRCode []byte `json:"qr_code"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type CodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// ChallengeResponse is returned instead of tokens when a login needs a second factor.
+type ChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	Token       string    `json:"mfa_token"`
+	Methods     []string  `json:"methods"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type BeginStepUpRequest struct {
+	UserID int64  `json:"-"`
+	Method string `json:"method" validate:"required"`
+	Locale string `json:"-"`
+	IP     string `json:"-"`
+}
+
+type SetPhoneRequest struct {
+	UserID int64 `json:"-"`
+	// Phone is an E.164 number. Spaces, dashes and brackets are ignored.
+	Phone  string  `json:"phone" validate:"required"`
+	MFA    *StepUp `json:"mfa,omitempty"`
+	Locale string  `json:"-"`
+	IP     string  `json:"-"`
+}
+
+// StepUp proves a second factor inline with a sensitive request.
+type StepUp struct {
+	Method string `json:"method" validate:"required"`
+	Code   string `json:"code,omitempty"`
+	// Token and Credential complete a MethodWebAuthn ceremony started with BeginStepUp.
+	Token      string          `json:"session_token,omitempty"`
+	Credential json.RawMessage `json:"credential,omitempty"`
+}
+
+// StepUpChallenge is the error data of a request that needs step-up.
+type StepUpChallenge struct {
+	Methods []string `json:"methods"`
+	// ACRValues and MaxAge are set when a route requires a recent authentication
+	// at that level: reauthenticate within the session to get a fresh token.
+	ACRValues string `json:"acr_values,omitempty"`
+	MaxAge    int    `json:"max_age,omitempty"`
+}
+
+// ChallengeTokenRequest prepares a method for completing an MFA challenge.
+type ChallengeTokenRequest struct {
+	Token string `json:"mfa_token" validate:"required"`
+	// Locale selects the language of email and SMS codes.
+	Locale string `json:"-"`
+	// IP is the client address SMS sends are throttled by.
+	IP string `json:"-"`
+}
+
+// StepUpRequest carries only a step-up, for operations that need nothing else.
+type StepUpRequest struct {
+	MFA *StepUp `json:"mfa,omitempty"`
+}
+
+type CompleteChallengeRequest struct {
+	Token  string `json:"mfa_token" validate:"required"`
+	Method string `json:"method" validate:"required"`
+	Code   string `json:"code" validate:"required_without=Credential"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get() for MethodWebAuthn.
+	Credential json.RawMessage `json:"credential,omitempty"`
+	// Client is the device the login finishes on. The session is started for it.
+	Client session.Client `json:"-"`
+}
@@ -0,0 +1,42 @@
+package passkey
+
+import "time"
+
+// Ceremonies a Session can be started for.
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+	CeremonyMFA          = "mfa"
+)
+
+// Credential is a WebAuthn public key credential registered by a user.
+type Credential struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"-"`
+	// CredentialID is the ID chosen by the authenticator.
+	CredentialID    []byte   `json:"credential_id"`
+	PublicKey       []byte   `json:"-"`
+	AttestationType string   `json:"-"`
+	Transports      []string `json:"transports"`
+	// AAGUID identifies the authenticator model.
+	AAGUID    []byte `json:"aaguid"`
+	SignCount uint32 `json:"-"`
+	// Flags are the raw authenticator data flags of the last ceremony.
+	Flags      uint8      `json:"-"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Session holds the state of a ceremony between its begin and finish steps.
+// Only the hash of its token is stored.
+type Session struct {
+	ID        int64
+	TokenHash string
+	Ceremony  string
+	// UserID is 0 for discoverable logins, where the user is not known up front.
+	UserID    int64
+	Data      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
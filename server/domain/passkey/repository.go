@@ -0,0 +1,23 @@
+package passkey
+
+import (
+	"context"
+	"time"
+)
+
+type CredentialRepository interface {
+	// Save stores a new credential. It returns repository.ErrConflict if the credential ID is already registered.
+	Save(ctx context.Context, c *Credential) (int64, error)
+	ListByUser(ctx context.Context, userID int64) ([]Credential, error)
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*Credential, error)
+	// UpdateUsage stores the sign count and flags reported by a successful login.
+	UpdateUsage(ctx context.Context, id int64, signCount uint32, flags uint8, at time.Time) error
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+type SessionRepository interface {
+	// Save stores a session, replacing any session with the same token hash.
+	Save(ctx context.Context, s *Session) error
+	// Take deletes and returns the session of the ceremony. It returns repository.ErrNotFound if there is none.
+	Take(ctx context.Context, hash, ceremony string) (*Session, error)
+}
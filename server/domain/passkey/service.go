@@ -0,0 +1,46 @@
+package passkey
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/LullNil/authx-go/domain/session"
+)
+
+type Service interface {
+	BeginRegistration(ctx context.Context, userID int64) (*Options, error)
+	FinishRegistration(ctx context.Context, req FinishRegistrationRequest) (*Credential, error)
+	// BeginLogin starts a username-less login with a discoverable credential.
+	BeginLogin(ctx context.Context) (*Options, error)
+	// FinishLogin verifies the assertion and returns the user it belongs to.
+	FinishLogin(ctx context.Context, req FinishLoginRequest) (int64, error)
+	// BeginAssertion starts using a passkey of the user as a second factor.
+	// The ceremony is bound to token, which the caller already handed out.
+	BeginAssertion(ctx context.Context, userID int64, token string) (*Options, error)
+	FinishAssertion(ctx context.Context, userID int64, token string, credential json.RawMessage) error
+	ListCredentials(ctx context.Context, userID int64) ([]Credential, error)
+	DeleteCredential(ctx context.Context, userID, id int64) error
+}
+
+// Options are passed to navigator.credentials.create() or .get() as the publicKey member.
+// Token identifies the ceremony in the finish request.
+type Options struct {
+	Token     string `json:"session_token,omitempty"`
+	PublicKey any    `json:"public_key"`
+}
+
+type FinishRegistrationRequest struct {
+	UserID int64  `json:"-"`
+	Token  string `json:"session_token" validate:"required"`
+	// Name lets the user tell their passkeys apart.
+	Name string `json:"name" validate:"max=64"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.create().
+	Credential json.RawMessage `json:"credential" validate:"required"`
+}
+
+type FinishLoginRequest struct {
+	Token string `json:"session_token" validate:"required"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get().
+	Credential json.RawMessage `json:"credential" validate:"required"`
+	Client     session.Client  `json:"-"`
+}
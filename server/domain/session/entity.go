@@ -0,0 +1,56 @@
+package session
+
+import (
+	"net/netip"
+	"time"
+)
+
+// Session is a login on one device. Its ID is the sid claim of access tokens
+// and the family ID of its refresh tokens.
+type Session struct {
+	ID        string `json:"id"`
+	UserID    int64  `json:"-"`
+	UserAgent string `json:"user_agent"`
+	// Browser, OS and Device are parsed from UserAgent.
+	Browser    string     `json:"browser,omitempty"`
+	OS         string     `json:"os,omitempty"`
+	Device     string     `json:"device"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// RememberMe selects the longer session policy.
+	RememberMe bool `json:"remember_me"`
+	// IdleTimeout ends the session when it is not used for this long.
+	// Zero means no idle timeout.
+	IdleTimeout time.Duration `json:"-"`
+	// Current marks the session the listing was requested from.
+	Current bool `json:"current"`
+}
+
+// Active reports whether the session is neither revoked, expired nor idle at now.
+func (s *Session) Active(now time.Time) bool {
+	if s.RevokedAt != nil || !now.Before(s.ExpiresAt) {
+		return false
+	}
+	return s.IdleTimeout == 0 || now.Before(s.LastSeenAt.Add(s.IdleTimeout))
+}
+
+// NormalizeIP returns ip in canonical form, or "" if it is not an IP address,
+// so that only addresses that fit the ip column are stored.
+func NormalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	// drop the zone, e.g. "%eth0", which can make an address arbitrarily long
+	return addr.WithZone("").Unmap().String()
+}
+
+// Client is the device a request comes from. Handlers fill it in; it is never
+// decoded from a request body.
+type Client struct {
+	UserAgent string
+	IP        string
+}
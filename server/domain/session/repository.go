@@ -0,0 +1,21 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Save(ctx context.Context, s *Session) error
+	// Get returns a session of the user. It returns repository.ErrNotFound if
+	// there is none with that ID.
+	Get(ctx context.Context, userID int64, id string) (*Session, error)
+	// ListActive returns the user's sessions that are neither revoked, expired
+	// nor idle at now, most recently seen first.
+	ListActive(ctx context.Context, userID int64, now time.Time) ([]Session, error)
+	// Touch records activity of the session from ip. It does nothing for
+	// sessions that are revoked or were idle at.
+	Touch(ctx context.Context, id, ip string, at time.Time) error
+	// Revoke marks the given sessions as revoked.
+	Revoke(ctx context.Context, at time.Time, ids ...string) error
+}
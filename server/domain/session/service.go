@@ -0,0 +1,24 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+type Service interface {
+	// List returns the user's active sessions, marking currentID as Current.
+	List(ctx context.Context, userID int64, currentID string) ([]Session, error)
+	// Revoke signs a device out. Its access tokens stop working immediately.
+	Revoke(ctx context.Context, req RevokeRequest) error
+	// RecordActivity notes that the session was used from ip. It only updates
+	// memory; FlushActivity writes what was recorded to the repository.
+	RecordActivity(id, ip string, at time.Time)
+	// FlushActivity saves recorded activity and returns the number of sessions updated.
+	FlushActivity(ctx context.Context) (int, error)
+}
+
+// RevokeRequest identifies a session of the authenticated user.
+type RevokeRequest struct {
+	UserID    int64
+	SessionID string
+}
@@ -0,0 +1,68 @@
+package token
+
+import (
+	"slices"
+	"time"
+)
+
+// Authentication context class references (acr) of access tokens.
+const (
+	// ACRSingleFactor is a login with a single factor, e.g. a password.
+	ACRSingleFactor = "aal1"
+	// ACRMultiFactor is a login with two factors, or a passkey with user verification.
+	ACRMultiFactor = "aal2"
+)
+
+// AMRMultiFactor is the amr value (RFC 8176) of an authentication that used more than one factor.
+const AMRMultiFactor = "mfa"
+
+// Authentication records how and when the user of a session last authenticated.
+// It is carried in access tokens as the amr and auth_time claims.
+type Authentication struct {
+	// Methods are the RFC 8176 amr values of the authentication, e.g. ["pwd", "otp", "mfa"].
+	Methods []string
+	Time    time.Time
+}
+
+// ACR returns the authentication context class the methods reach.
+func (a Authentication) ACR() string {
+	if slices.Contains(a.Methods, AMRMultiFactor) {
+		return ACRMultiFactor
+	}
+	return ACRSingleFactor
+}
+
+// RefreshToken is an opaque refresh token as stored in the database.
+// Tokens issued by rotating each other form a family that shares FamilyID.
+type RefreshToken struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"user_id"`
+	FamilyID        string     `json:"family_id"`
+	TokenHash       string     `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	FamilyExpiresAt time.Time  `json:"family_expires_at"`
+	RotatedAt       *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	// Auth is how the session was authenticated. Rotations keep it.
+	Auth Authentication `json:"-"`
+}
+
+// Pair is an access token together with the refresh token that can renew it.
+type Pair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        string    `json:"session_id"`
+}
+
+// AccessTokenKey returns the denylist key revoking a single access token.
+func AccessTokenKey(jti string) string {
+	return "jti:" + jti
+}
+
+// SessionKey returns the denylist key revoking every access token of a session.
+func SessionKey(sessionID string) string {
+	return "sid:" + sessionID
+}
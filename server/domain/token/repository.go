@@ -0,0 +1,41 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+type Saver interface {
+	Save(ctx context.Context, t *RefreshToken) (int64, error)
+	// Rotate marks the token with oldID as rotated and saves next in a single transaction.
+	// It returns repository.ErrConflict if the old token was already rotated or revoked.
+	Rotate(ctx context.Context, oldID int64, next *RefreshToken) (int64, error)
+}
+
+type Getter interface {
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// GetActiveByFamily returns the token of the family that has been neither
+	// rotated nor revoked. It returns repository.ErrNotFound if there is none.
+	GetActiveByFamily(ctx context.Context, familyID string) (*RefreshToken, error)
+}
+
+type Revoker interface {
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeByUser revokes every active token of the user, except those of keepFamilyID
+	// when it is not empty, and returns the affected family IDs.
+	RevokeByUser(ctx context.Context, userID int64, keepFamilyID string, at time.Time) ([]string, error)
+}
+
+type Repository interface {
+	Saver
+	Getter
+	Revoker
+}
+
+// Denylist stores revoked access token identifiers until the tokens they refer to expire.
+type Denylist interface {
+	Add(ctx context.Context, key string, expiresAt time.Time) error
+	// Contains reports which of the given keys are revoked.
+	Contains(ctx context.Context, keys ...string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
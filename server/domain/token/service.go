@@ -0,0 +1,46 @@
+package token
+
+import (
+	"context"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/session"
+)
+
+type Service interface {
+	// Issue starts a new session for the user and returns its first pair.
+	Issue(ctx context.Context, req IssueRequest) (*Pair, error)
+	// Reauthenticate records a new authentication for the session and returns a pair
+	// carrying it. The session's current refresh token is rotated.
+	Reauthenticate(ctx context.Context, userID int64, sessionID string, auth Authentication) (*Pair, error)
+	// Refresh rotates the given refresh token and returns a new pair.
+	// The session is marked as seen from client. Idle sessions cannot be refreshed.
+	Refresh(ctx context.Context, refreshToken string, client session.Client) (*Pair, error)
+	// Revoke ends a single session: its refresh token family and the presented access token.
+	Revoke(ctx context.Context, req RevokeRequest) error
+	// RevokeAll ends every session of the user.
+	RevokeAll(ctx context.Context, req RevokeRequest) error
+	// RevokeOthers ends every session of the user except the one the request was made from.
+	RevokeOthers(ctx context.Context, req RevokeRequest) error
+	// IsRevoked reports whether an access token or its session has been revoked.
+	IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error)
+	// PurgeExpired deletes denylist entries for tokens that have expired.
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+// IssueRequest starts a session of the user on the client it authenticated from.
+type IssueRequest struct {
+	UserID int64
+	Auth   Authentication
+	Client session.Client
+	// RememberMe selects the longer session policy.
+	RememberMe bool
+}
+
+// RevokeRequest identifies the access token used to request a revocation.
+type RevokeRequest struct {
+	UserID         int64
+	SessionID      string
+	TokenID        string
+	TokenExpiresAt time.Time
+}
@@ -1,8 +1,59 @@
 package user
 
+import "time"
+
 type User struct {
 	ID       int64  `json:"id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"-"`
+	// EmailVerifiedAt is nil until the user confirms their email address.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Phone is an E.164 number SMS codes are sent to. It can only be used
+	// for sign-in once PhoneVerifiedAt is set.
+	Phone           *string    `json:"phone,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+	// PendingPhone is a new number waiting for its code. It replaces Phone
+	// only once verified, so a typo can't take away a working number.
+	PendingPhone *string `json:"pending_phone,omitempty"`
+}
+
+// PasswordResetToken is a single-use token sent by email to reset a forgotten password.
+// Only the hash of the token is stored.
+type PasswordResetToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// EmailChange is a pending or completed change of a user's email address.
+// The new address is confirmed with one token; the old address receives
+// another that cancels or reverts the change.
+type EmailChange struct {
+	ID               int64
+	UserID           int64
+	OldEmail         string
+	NewEmail         string
+	ConfirmTokenHash string
+	RevertTokenHash  string
+	CreatedAt        time.Time
+	ConfirmExpiresAt time.Time
+	RevertExpiresAt  time.Time
+	ConfirmedAt      *time.Time
+	RevertedAt       *time.Time
+}
+
+// MagicLinkToken is a single-use login link. It is bound to the browser that
+// requested it by a nonce kept in a cookie. Only hashes are stored.
+type MagicLinkToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	NonceHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
 }
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 )
 
 type Saver interface {
@@ -14,7 +15,67 @@ type Getter interface {
 	GetByUsername(ctx context.Context, username string) (*User, error)
 }
 
+type Updater interface {
+	UpdatePassword(ctx context.Context, id int64, hash string) error
+	// MarkEmailVerified marks the email as verified if it is still the user's email.
+	// It returns repository.ErrNotFound otherwise.
+	MarkEmailVerified(ctx context.Context, id int64, email string, at time.Time) error
+	// SetPendingPhone stores a number to verify, leaving the current phone untouched.
+	SetPendingPhone(ctx context.Context, id int64, phone string) error
+	// MarkPhoneVerified makes the pending number the user's verified phone if it
+	// is still pending. It returns repository.ErrNotFound otherwise, and
+	// repository.ErrConflict if another user has verified the number.
+	MarkPhoneVerified(ctx context.Context, id int64, phone string, at time.Time) error
+}
+
 type Repository interface {
 	Saver
 	Getter
+	Updater
+}
+
+// PasswordHistoryRepository keeps the most recent password hashes of each user.
+type PasswordHistoryRepository interface {
+	// Add records a hash and drops entries beyond the newest keep ones or older than notBefore.
+	Add(ctx context.Context, userID int64, hash string, keep int, notBefore time.Time) error
+	// List returns up to limit hashes recorded since notBefore, newest first.
+	List(ctx context.Context, userID int64, limit int, notBefore time.Time) ([]string, error)
+}
+
+// PasswordResetRepository stores password reset tokens.
+type PasswordResetRepository interface {
+	Save(ctx context.Context, t *PasswordResetToken) (int64, error)
+	GetByHash(ctx context.Context, hash string) (*PasswordResetToken, error)
+	// MarkUsed consumes the token. It returns repository.ErrConflict if it was already used.
+	MarkUsed(ctx context.Context, id int64, at time.Time) error
+	// InvalidateByUser consumes every outstanding token of the user.
+	InvalidateByUser(ctx context.Context, userID int64, at time.Time) error
+}
+
+// EmailChangeRepository stores email address changes.
+type EmailChangeRepository interface {
+	Save(ctx context.Context, c *EmailChange) (int64, error)
+	GetByConfirmHash(ctx context.Context, hash string) (*EmailChange, error)
+	GetByRevertHash(ctx context.Context, hash string) (*EmailChange, error)
+	// DeletePending removes the user's unconfirmed, unreverted changes.
+	DeletePending(ctx context.Context, userID int64) error
+	// Confirm marks the change as confirmed and sets the user's email to the new,
+	// verified address. It returns repository.ErrNotFound if the change was already
+	// handled or the user's email is no longer the old address, and
+	// repository.ErrConflict if the new address belongs to another user.
+	Confirm(ctx context.Context, id int64, at time.Time) error
+	// Revert marks the change as reverted and, if it was confirmed, restores the
+	// old address. It returns repository.ErrNotFound if the change was already
+	// reverted and repository.ErrConflict if the old address was taken meanwhile.
+	Revert(ctx context.Context, id int64, at time.Time) error
+}
+
+// MagicLinkRepository stores magic link login tokens.
+type MagicLinkRepository interface {
+	Save(ctx context.Context, t *MagicLinkToken) (int64, error)
+	GetByHash(ctx context.Context, hash string) (*MagicLinkToken, error)
+	// MarkUsed consumes the token. It returns repository.ErrConflict if it was already used.
+	MarkUsed(ctx context.Context, id int64, at time.Time) error
+	// InvalidateByUser consumes every outstanding token of the user.
+	InvalidateByUser(ctx context.Context, userID int64, at time.Time) error
 }
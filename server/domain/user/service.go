@@ -2,11 +2,41 @@ package user
 
 import (
 	"context"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/session"
 )
 
 type Service interface {
 	RegisterUser(ctx context.Context, req RegisterUserRequest) (int64, error)
-	LoginUser(ctx context.Context, req LoginRequest) (string, error)
+	LoginUser(ctx context.Context, req LoginRequest) (*LoginResponse, error)
+	RefreshTokens(ctx context.Context, req RefreshRequest) (*LoginResponse, error)
+	LogoutUser(ctx context.Context, req LogoutRequest) error
+	ChangePassword(ctx context.Context, req ChangePasswordRequest) error
+	// ForgotPassword emails a reset link if the account exists. It never reports whether it does.
+	ForgotPassword(ctx context.Context, req ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req ResetPasswordRequest) error
+	VerifyEmail(ctx context.Context, req VerifyEmailRequest) error
+	// ResendVerification emails a new verification link if the account exists and is unverified.
+	// It never reports whether it does.
+	ResendVerification(ctx context.Context, req ResendVerificationRequest) error
+	// ChangeEmail starts an email change that takes effect once the new address is confirmed.
+	ChangeEmail(ctx context.Context, req ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, req EmailChangeTokenRequest) error
+	// RevertEmailChange cancels a pending change, or undoes a confirmed one, and ends every session.
+	RevertEmailChange(ctx context.Context, req EmailChangeTokenRequest) error
+	// RequestMagicLink emails a login link if the account exists. It never reports whether it does.
+	RequestMagicLink(ctx context.Context, req MagicLinkRequest) error
+	LoginWithMagicLink(ctx context.Context, req MagicLinkLoginRequest) (*LoginResponse, error)
+	// LoginWithPasskey finishes a username-less passkey login.
+	LoginWithPasskey(ctx context.Context, req passkey.FinishLoginRequest) (*LoginResponse, error)
+	// CompleteMFALogin finishes a login that returned an MFA challenge.
+	CompleteMFALogin(ctx context.Context, req mfa.CompleteChallengeRequest) (*LoginResponse, error)
+	// Reauthenticate checks the user's credentials again and returns tokens for
+	// the same session with a fresh auth_time, to satisfy step-up requirements.
+	Reauthenticate(ctx context.Context, req ReauthenticateRequest) (*LoginResponse, error)
 	GetUserByID(ctx context.Context, id int64) (*User, error)
 	// GetUserByEmail(ctx context.Context, email string) (*User, error)
 }
@@ -15,9 +45,111 @@ type RegisterUserRequest struct {
 	Email    string `json:"email" validate:"required"`
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// Locale selects the language of the verification email. It is taken from the Accept-Language header.
+	Locale string `json:"-"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// RememberMe selects the longer session policy.
+	RememberMe bool           `json:"remember_me"`
+	Client     session.Client `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string         `json:"refresh_token" validate:"required"`
+	Client       session.Client `json:"-"`
+}
+
+// LogoutRequest identifies the session to end. It is built from the
+// authenticated access token rather than decoded from the request body.
+type LogoutRequest struct {
+	UserID         int64
+	SessionID      string
+	TokenID        string
+	TokenExpiresAt time.Time
+	AllSessions    bool
+}
+
+// ChangePasswordRequest changes the password of the authenticated user.
+// UserID and SessionID come from the access token, not from the request body.
+type ChangePasswordRequest struct {
+	UserID              int64  `json:"-"`
+	SessionID           string `json:"-"`
+	CurrentPassword     string `json:"current_password" validate:"required"`
+	NewPassword         string `json:"new_password" validate:"required"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+	// MFA is required when the user has a second factor enabled.
+	MFA *mfa.StepUp `json:"mfa,omitempty"`
+}
+
+// ReauthenticateRequest proves the user's credentials again within a session.
+// UserID and SessionID come from the access token, not from the request body.
+type ReauthenticateRequest struct {
+	UserID    int64  `json:"-"`
+	SessionID string `json:"-"`
+	// Password is required from users without a second factor. Users with one
+	// send it too to reach aal2; a second factor alone only gives aal1.
+	Password string `json:"password,omitempty"`
+	// MFA is required when the user has a second factor enabled.
+	MFA *mfa.StepUp `json:"mfa,omitempty"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	// Locale selects the language of the email. It is taken from the Accept-Language header.
+	Locale string `json:"-"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email  string `json:"email" validate:"required,email"`
+	Locale string `json:"-"`
+}
+
+// ChangeEmailRequest changes the email of the authenticated user.
+// UserID comes from the access token, not from the request body.
+type ChangeEmailRequest struct {
+	UserID   int64  `json:"-"`
+	NewEmail string `json:"new_email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Locale   string `json:"-"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// MagicLinkRequest asks for a login link. Nonce is generated by the handler and
+// stored in a cookie of the requesting browser.
+type MagicLinkRequest struct {
+	Email  string `json:"email" validate:"required,email"`
+	Nonce  string `json:"-"`
+	Locale string `json:"-"`
+}
+
+// MagicLinkLoginRequest is built from the link's query and the nonce cookie.
+type MagicLinkLoginRequest struct {
+	Token  string `validate:"required"`
+	Nonce  string `validate:"required"`
+	Client session.Client
+}
+
+type LoginResponse struct {
+	AccessToken      string    `json:"access_token,omitempty"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	// MFA is set instead of the tokens when the login needs a second factor.
+	MFA *mfa.ChallengeResponse `json:"-"`
 }
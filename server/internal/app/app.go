@@ -5,16 +5,40 @@ import (
 	"database/sql"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/LullNil/authx-go/config"
+	domainMFA "github.com/LullNil/authx-go/domain/mfa"
+	domainPasskey "github.com/LullNil/authx-go/domain/passkey"
+	domainSession "github.com/LullNil/authx-go/domain/session"
+	domainToken "github.com/LullNil/authx-go/domain/token"
 	domainUser "github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/delivery/http/authcookie"
+	"github.com/LullNil/authx-go/internal/delivery/http/jwks"
+	"github.com/LullNil/authx-go/internal/delivery/http/mfa"
+	"github.com/LullNil/authx-go/internal/delivery/http/middleware"
+	"github.com/LullNil/authx-go/internal/delivery/http/passkey"
+	"github.com/LullNil/authx-go/internal/delivery/http/session"
 	"github.com/LullNil/authx-go/internal/delivery/http/user"
+	"github.com/LullNil/authx-go/internal/lib/jwt"
 	"github.com/LullNil/authx-go/internal/lib/logger"
+	"github.com/LullNil/authx-go/internal/lib/mailer"
+	"github.com/LullNil/authx-go/internal/lib/password"
+	"github.com/LullNil/authx-go/internal/lib/passwordpolicy"
+	"github.com/LullNil/authx-go/internal/lib/ratelimit"
+	"github.com/LullNil/authx-go/internal/lib/secretbox"
+	"github.com/LullNil/authx-go/internal/lib/signedtoken"
+	"github.com/LullNil/authx-go/internal/lib/sms"
+	"github.com/LullNil/authx-go/internal/repository/cache"
 	"github.com/LullNil/authx-go/internal/repository/postgres"
+	mfas "github.com/LullNil/authx-go/internal/service/mfa"
+	passkeys "github.com/LullNil/authx-go/internal/service/passkey"
+	sessions "github.com/LullNil/authx-go/internal/service/session"
+	tokens "github.com/LullNil/authx-go/internal/service/token"
 	users "github.com/LullNil/authx-go/internal/service/user"
 
 	"github.com/go-chi/chi"
@@ -29,7 +53,11 @@ const (
 )
 
 type Services struct {
-	User domainUser.Service
+	User    domainUser.Service
+	Token   domainToken.Service
+	MFA     domainMFA.Service
+	Passkey domainPasskey.Service
+	Session domainSession.Service
 }
 
 // Run starts the application.
@@ -50,11 +78,52 @@ func Run(cfg *config.Config) error {
 		db.Close()
 	}()
 
+	// Init token signing keys
+	keyManager, err := jwt.NewKeyManager(cfg.JWT, log)
+	if err != nil {
+		return err
+	}
+
+	// Init password hasher
+	passwordHasher, err := password.New(cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	// Init password policy
+	passwordPolicy, err := passwordpolicy.New(cfg.Password, log)
+	if err != nil {
+		return err
+	}
+	defer passwordPolicy.Close()
+
+	// Init mailer
+	mail, err := mailer.New(cfg.Mail, log)
+	if err != nil {
+		return err
+	}
+	mailTemplates, err := mailer.NewTemplates(cfg.Mail.TemplatesDir, cfg.Mail.DefaultLocale)
+	if err != nil {
+		return err
+	}
+
+	// Init SMS sender
+	smsSender, err := sms.New(cfg.SMS, log)
+	if err != nil {
+		return err
+	}
+
 	// Init app services
-	appServices := initAppServices(db, log)
+	appServices, err := initAppServices(cfg, db, keyManager, passwordHasher, passwordPolicy, mail, mailTemplates, smsSender, log)
+	if err != nil {
+		return err
+	}
+
+	// Init token verifier
+	tokenVerifier := jwt.NewVerifier(cfg.JWT, keyManager)
 
 	// Init router
-	router := initRouter(log, appServices)
+	router := initRouter(cfg, log, appServices, tokenVerifier, keyManager)
 
 	// Create errgroup for managing server goroutines
 	group, gCtx := errgroup.WithContext(ctx)
@@ -67,6 +136,22 @@ func Run(cfg *config.Config) error {
 		WriteTimeout: cfg.HTTPServer.WriteTimeout,
 	}
 
+	// Start background jobs
+	group.Go(func() error {
+		keyManager.Run(gCtx, cfg.JWT.KeyringReloadInterval)
+		return nil
+	})
+
+	group.Go(func() error {
+		runDenylistCleanup(gCtx, appServices.Token, cfg.Revocation.CleanupInterval, log)
+		return nil
+	})
+
+	group.Go(func() error {
+		runActivityFlush(gCtx, appServices.Session, cfg.Session.ActivityFlushInterval, log)
+		return nil
+	})
+
 	group.Go(func() error {
 		log.Info("starting http server...", slog.String("port", cfg.HTTPServer.Port))
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -112,27 +197,126 @@ func initPostgres(cfg *config.Config, log *slog.Logger) (*sql.DB, error) {
 }
 
 // initAppServices initializes the application services.
-func initAppServices(db *sql.DB, log *slog.Logger) *Services {
+func initAppServices(cfg *config.Config, db *sql.DB, keyManager *jwt.KeyManager, passwordHasher *password.Manager, passwordPolicy *passwordpolicy.Policy, mail mailer.Mailer, mailTemplates *mailer.Templates, smsSender sms.Sender, log *slog.Logger) (*Services, error) {
 	// Init repositories
 	userRepo := postgres.NewUserRepository(db)
+	passwordHistoryRepo := postgres.NewPasswordHistoryRepository(db)
+	passwordResetRepo := postgres.NewPasswordResetRepository(db)
+	emailChangeRepo := postgres.NewEmailChangeRepository(db)
+	magicLinkRepo := postgres.NewMagicLinkRepository(db)
+	totpRepo := postgres.NewTOTPRepository(db)
+	recoveryCodeRepo := postgres.NewRecoveryCodeRepository(db)
+	mfaChallengeRepo := postgres.NewMFAChallengeRepository(db)
+	mfaEmailRepo := postgres.NewMFAEmailRepository(db)
+	mfaSMSRepo := postgres.NewMFASMSRepository(db)
+	oneTimeCodeRepo := postgres.NewOneTimeCodeRepository(db)
+	passkeyCredentialRepo := postgres.NewPasskeyCredentialRepository(db)
+	passkeySessionRepo := postgres.NewPasskeySessionRepository(db)
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
+	sessionRepo := postgres.NewSessionRepository(db)
+	denylist := cache.NewDenylist(
+		postgres.NewDenylistRepository(db),
+		cfg.Revocation.CacheSize,
+		cfg.Revocation.NegativeCacheTTL,
+	)
+
+	// Init token issuer
+	tokenIssuer := jwt.NewIssuer(cfg.JWT, keyManager)
+
+	// Init email link signer
+	linkSigner, err := signedtoken.New(cfg.EmailVerification.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Init MFA secret encryption
+	mfaSecrets, err := secretbox.NewFromBase64(cfg.MFA.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Init WebAuthn relying party
+	relyingParty, err := passkeys.NewRelyingParty(cfg.WebAuthn)
+	if err != nil {
+		return nil, err
+	}
 
 	// Init services
-	userSvc := users.NewService(userRepo, log)
+	tokenSvc := tokens.NewService(refreshTokenRepo, sessionRepo, denylist, userRepo, tokenIssuer, cfg.RefreshToken, cfg.Session, log)
+	sessionSvc := sessions.NewService(sessionRepo, tokenSvc, log)
+	passkeySvc := passkeys.NewService(passkeyCredentialRepo, passkeySessionRepo, userRepo, relyingParty, cfg.WebAuthn, log)
+	mfaSvc := mfas.NewService(
+		totpRepo,
+		recoveryCodeRepo,
+		mfaChallengeRepo,
+		mfaEmailRepo,
+		mfaSMSRepo,
+		oneTimeCodeRepo,
+		passkeySvc,
+		userRepo,
+		mfaSecrets,
+		mail,
+		mailTemplates,
+		smsSender,
+		cfg.MFA,
+		cfg.RateLimit.CacheSize,
+		log,
+	)
+	userSvc := users.NewService(
+		userRepo,
+		passwordHistoryRepo,
+		passwordResetRepo,
+		emailChangeRepo,
+		magicLinkRepo,
+		tokenSvc,
+		mfaSvc,
+		passkeySvc,
+		passwordHasher,
+		passwordPolicy,
+		mail,
+		mailTemplates,
+		linkSigner,
+		cfg,
+		log,
+	)
 
 	return &Services{
-		User: userSvc,
-	}
+		User:    userSvc,
+		Token:   tokenSvc,
+		MFA:     mfaSvc,
+		Passkey: passkeySvc,
+		Session: sessionSvc,
+	}, nil
 }
 
 // initRouter initializes the router.
-func initRouter(log *slog.Logger, services *Services) http.Handler {
+func initRouter(cfg *config.Config, log *slog.Logger, services *Services, tokenVerifier middleware.Verifier, keyManager *jwt.KeyManager) http.Handler {
+	// Init token cookie transport
+	cookies := authcookie.New(cfg.Cookie)
+
 	// Init handlers
-	userHandler := user.New(services.User, log)
+	userHandler := user.New(services.User, cookies, cfg.MagicLink, log)
+	mfaHandler := mfa.New(services.MFA, services.User, log)
+	passkeyHandler := passkey.New(services.Passkey, log)
+	sessionHandler := session.New(services.Session, cookies, log)
+	jwksHandler := jwks.New(keyManager, cfg.JWT.JWKSMaxAge, log)
+
+	// Init middlewares
+	authenticate := middleware.Authenticate(tokenVerifier, services.Token, cfg.Cookie.AccessName, log)
+	rateLimit := middleware.RateLimit(ratelimit.New(cfg.RateLimit.Requests, cfg.RateLimit.Window, cfg.RateLimit.CacheSize), log)
+	trackActivity := middleware.TrackActivity(services.Session)
+	recentMFA := middleware.RequireStepUp(domainToken.ACRMultiFactor, cfg.MFA.StepUpMaxAge, services.MFA, log)
+
+	trustedProxies := make([]netip.Prefix, 0, len(cfg.HTTPServer.TrustedProxies))
+	for _, cidr := range cfg.HTTPServer.TrustedProxies {
+		// Already validated when the config was loaded
+		trustedProxies = append(trustedProxies, netip.MustParsePrefix(cidr))
+	}
 
 	// Setup router
 	router := chi.NewRouter()
 	router.Use(chimiddleware.RequestID)
-	router.Use(chimiddleware.RealIP)
+	router.Use(middleware.RealIP(trustedProxies))
 	router.Use(chimiddleware.Recoverer)
 	router.Use(cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // TODO: add allowed origins
@@ -148,19 +332,134 @@ func initRouter(log *slog.Logger, services *Services) http.Handler {
 		AllowedHeaders: []string{
 			"Content-Type",
 			"Authorization",
+			cfg.Cookie.CSRFHeader,
 		},
 	}).Handler)
+	router.Use(middleware.CSRF(cookies, log))
+
+	// Well-known routes
+	router.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
 	// User routes
 	router.Route("/user", func(r chi.Router) {
 		r.Post("/register", userHandler.RegisterUser)
 		r.Post("/login", userHandler.LoginUser)
-		// r.Get("/info", userHandler.GetUserInfo)
+		r.Post("/refresh", userHandler.RefreshTokens)
+		r.With(rateLimit).Post("/login/mfa", userHandler.CompleteMFALogin)
+		r.With(rateLimit).Post("/login/mfa/webauthn", mfaHandler.BeginWebAuthn)
+		r.With(rateLimit).Post("/login/mfa/email", mfaHandler.SendLoginCode)
+		r.With(rateLimit).Post("/login/mfa/sms", mfaHandler.SendLoginSMS)
+		r.With(rateLimit).Post("/login/passkey/begin", passkeyHandler.BeginLogin)
+		r.With(rateLimit).Post("/login/passkey/finish", userHandler.LoginWithPasskey)
+		r.With(rateLimit).Post("/password/forgot", userHandler.ForgotPassword)
+		r.Post("/password/reset", userHandler.ResetPassword)
+		r.Get("/email/verify", userHandler.VerifyEmail)
+		r.Post("/email/verify", userHandler.VerifyEmail)
+		r.With(rateLimit).Post("/email/resend", userHandler.ResendVerification)
+		r.Post("/email/confirm", userHandler.ConfirmEmailChange)
+		r.Post("/email/revert", userHandler.RevertEmailChange)
+
+		// Passwordless login
+		if cfg.MagicLink.Enabled {
+			r.With(rateLimit).Post("/login/magic", userHandler.RequestMagicLink)
+			r.Get("/login/magic/callback", userHandler.MagicLinkCallback)
+		}
+
+		// Protected routes
+		r.Group(func(r chi.Router) {
+			r.Use(authenticate)
+			r.Use(trackActivity)
+			r.Get("/info", userHandler.GetUserInfo)
+			r.Post("/logout", userHandler.LogoutUser)
+			r.Post("/logout-all", userHandler.LogoutAll)
+			r.With(rateLimit).Post("/reauthenticate", userHandler.Reauthenticate)
+			r.Post("/password", userHandler.ChangePassword)
+			r.With(recentMFA).Post("/email", userHandler.ChangeEmail)
+			r.Post("/phone", mfaHandler.SetPhone)
+			r.Post("/phone/verify", mfaHandler.VerifyPhone)
+
+			// Two-factor authentication
+			r.Route("/mfa", func(r chi.Router) {
+				r.Post("/totp/setup", mfaHandler.SetupTOTP)
+				r.Post("/totp/confirm", mfaHandler.ConfirmTOTP)
+				r.Post("/totp/disable", mfaHandler.DisableTOTP)
+				r.Post("/email/setup", mfaHandler.SetupEmailOTP)
+				r.Post("/email/confirm", mfaHandler.ConfirmEmailOTP)
+				r.Post("/email/disable", mfaHandler.DisableEmailOTP)
+				r.Post("/sms/enable", mfaHandler.EnableSMSOTP)
+				r.Post("/sms/disable", mfaHandler.DisableSMSOTP)
+				r.Post("/step-up", mfaHandler.BeginStepUp)
+				r.Get("/recovery-codes", mfaHandler.RecoveryCodesRemaining)
+				r.With(recentMFA).Post("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+			})
+
+			// Passkeys
+			r.Route("/passkeys", func(r chi.Router) {
+				r.Get("/", passkeyHandler.ListCredentials)
+				r.Post("/register/begin", passkeyHandler.BeginRegistration)
+				r.Post("/register/finish", passkeyHandler.FinishRegistration)
+				r.With(recentMFA).Delete("/{id}", passkeyHandler.DeleteCredential)
+			})
+
+			// Sessions
+			r.Route("/sessions", func(r chi.Router) {
+				r.Get("/", sessionHandler.List)
+				r.Delete("/{id}", sessionHandler.Revoke)
+			})
+		})
 	})
 
 	return router
 }
 
+// runDenylistCleanup periodically purges expired revoked token entries until ctx is done.
+func runDenylistCleanup(ctx context.Context, tokenSvc domainToken.Service, interval time.Duration, log *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := tokenSvc.PurgeExpired(ctx)
+			if err != nil {
+				log.Error("failed to purge revoked tokens", slog.String("error", err.Error()))
+				continue
+			}
+			log.Debug("purged revoked tokens", slog.Int64("count", n))
+		}
+	}
+}
+
+// runActivityFlush periodically saves the recorded last activity of sessions,
+// and once more when ctx is done so that shutting down loses none of it.
+func runActivityFlush(ctx context.Context, sessionSvc domainSession.Service, interval time.Duration, log *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	flush := func(ctx context.Context) {
+		n, err := sessionSvc.FlushActivity(ctx)
+		if err != nil {
+			log.Error("failed to save session activity", slog.String("error", err.Error()))
+			return
+		}
+		log.Debug("saved session activity", slog.Int("count", n))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
 func setupLogger(env string) *slog.Logger {
 	switch env {
 	case envLocal:
@@ -0,0 +1,181 @@
+package authcookie
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+)
+
+const (
+	TransportBearer = "bearer"
+	TransportCookie = "cookie"
+	TransportBoth   = "both"
+)
+
+// Transport writes and reads auth tokens as cookies.
+type Transport struct {
+	cfg      config.Cookie
+	sameSite http.SameSite
+}
+
+// New returns a new cookie transport.
+func New(cfg config.Cookie) *Transport {
+	return &Transport{
+		cfg:      cfg,
+		sameSite: parseSameSite(cfg.SameSite),
+	}
+}
+
+// Enabled reports whether tokens are delivered in cookies.
+func (t *Transport) Enabled() bool {
+	return t.cfg.Transport == TransportCookie || t.cfg.Transport == TransportBoth
+}
+
+// InBody reports whether tokens are returned in response bodies.
+func (t *Transport) InBody() bool {
+	return !t.Enabled() || t.cfg.Transport == TransportBoth
+}
+
+// AccessName returns the name of the access token cookie.
+func (t *Transport) AccessName() string {
+	return t.cfg.AccessName
+}
+
+// SetTokens sets the access and refresh token cookies along with a fresh CSRF token.
+func (t *Transport) SetTokens(w http.ResponseWriter, accessToken string, accessExpiresAt time.Time, refreshToken string, refreshExpiresAt time.Time) error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	csrf, err := securetoken.New()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, t.cookie(t.cfg.AccessName, accessToken, t.cfg.Path, accessExpiresAt, true))
+	http.SetCookie(w, t.cookie(t.cfg.RefreshName, refreshToken, t.cfg.RefreshPath, refreshExpiresAt, true))
+	// The CSRF cookie must be readable by scripts so it can be echoed in a header.
+	http.SetCookie(w, t.cookie(t.cfg.CSRFName, csrf, t.cfg.Path, refreshExpiresAt, false))
+
+	return nil
+}
+
+// Clear expires all auth cookies.
+func (t *Transport) Clear(w http.ResponseWriter) {
+	if !t.Enabled() {
+		return
+	}
+
+	for _, c := range []*http.Cookie{
+		t.cookie(t.cfg.AccessName, "", t.cfg.Path, time.Time{}, true),
+		t.cookie(t.cfg.RefreshName, "", t.cfg.RefreshPath, time.Time{}, true),
+		t.cookie(t.cfg.CSRFName, "", t.cfg.Path, time.Time{}, false),
+	} {
+		c.MaxAge = -1
+		http.SetCookie(w, c)
+	}
+}
+
+// SetNonce sets a cookie binding a flow to the browser that started it, e.g. a magic link login.
+// It is set regardless of the token transport and always uses SameSite=Lax, since it must be
+// sent when the user follows a link from their mail client.
+func (t *Transport) SetNonce(w http.ResponseWriter, name, value, path string, expires time.Time) {
+	c := t.cookie(name, value, path, expires, true)
+	c.SameSite = http.SameSiteLaxMode
+	http.SetCookie(w, c)
+}
+
+// Nonce returns the value of a nonce cookie set by SetNonce, if any.
+func (t *Transport) Nonce(r *http.Request, name string) string {
+	if c, err := r.Cookie(name); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ClearNonce expires a nonce cookie.
+func (t *Transport) ClearNonce(w http.ResponseWriter, name, path string) {
+	c := t.cookie(name, "", path, time.Time{}, true)
+	c.SameSite = http.SameSiteLaxMode
+	c.MaxAge = -1
+	http.SetCookie(w, c)
+}
+
+// RefreshToken returns the refresh token cookie of the request, if any.
+func (t *Transport) RefreshToken(r *http.Request) string {
+	if !t.Enabled() {
+		return ""
+	}
+	if c, err := r.Cookie(t.cfg.RefreshName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// HasAuthCookie reports whether the request carries an access or refresh token cookie.
+func (t *Transport) HasAuthCookie(r *http.Request) bool {
+	for _, name := range []string{t.cfg.AccessName, t.cfg.RefreshName} {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRF rejects state-changing requests authenticated by cookie unless they
+// echo the CSRF cookie in the CSRF header (double-submit cookie pattern).
+// Requests using the Authorization header are not exposed to CSRF and pass through.
+func (t *Transport) CSRF(onFailure http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !t.Enabled() || isSafeMethod(r.Method) || r.Header.Get("Authorization") != "" || !t.HasAuthCookie(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			c, err := r.Cookie(t.cfg.CSRFName)
+			header := r.Header.Get(t.cfg.CSRFHeader)
+			if err != nil || c.Value == "" || header == "" || !securetoken.Equal(c.Value, header) {
+				onFailure(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (t *Transport) cookie(name, value, path string, expires time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   t.cfg.Domain,
+		Expires:  expires,
+		Secure:   t.cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: t.sameSite,
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func parseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
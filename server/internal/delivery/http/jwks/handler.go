@@ -0,0 +1,41 @@
+package jwks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/lib/jwt"
+)
+
+type Handler struct {
+	keys   jwt.PublicKeySource
+	maxAge time.Duration
+	log    *slog.Logger
+}
+
+// New returns a new JWKS handler.
+func New(keys jwt.PublicKeySource, maxAge time.Duration, log *slog.Logger) *Handler {
+	return &Handler{
+		keys:   keys,
+		maxAge: maxAge,
+		log:    log,
+	}
+}
+
+// GetJWKS publishes the public keys tokens may be verified with.
+// The set is written as a bare JWKS document, as consumers expect, not wrapped in the API envelope.
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.jwks.GetJWKS"
+
+	set := jwt.BuildJWKS(h.keys.PublicKeys())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(set); err != nil {
+		h.log.Error("failed to write jwks", slog.String("op", op), slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,493 @@
+package mfa
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/delivery/http/middleware"
+	"github.com/LullNil/authx-go/internal/lib/mailer"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+)
+
+type Handler struct {
+	mfaService  mfa.Service
+	userService user.Service
+	log         *slog.Logger
+}
+
+// New returns a new MFA handler.
+func New(mfaService mfa.Service, userService user.Service, log *slog.Logger) *Handler {
+	return &Handler{
+		mfaService:  mfaService,
+		userService: userService,
+		log:         log,
+	}
+}
+
+// SetupTOTP starts an authenticator app enrolment for the current user.
+func (h *Handler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.SetupTOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Label the entry in the app with the user's email
+	u, err := h.userService.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Call service
+	setup, err := h.mfaService.SetupTOTP(r.Context(), principal.UserID, u.Email)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, setup)
+}
+
+// ConfirmTOTP enables TOTP for the current user and returns the recovery codes.
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.ConfirmTOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.CodeRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	codes, err := h.mfaService.ConfirmTOTP(r.Context(), principal.UserID, req.Code)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, codes)
+}
+
+// DisableTOTP turns TOTP off for the current user.
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.DisableTOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.CodeRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.DisableTOTP(r.Context(), principal.UserID, req.Code); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// BeginWebAuthn returns the options for completing an MFA challenge with a passkey.
+func (h *Handler) BeginWebAuthn(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.BeginWebAuthn"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.ChallengeTokenRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	opts, err := h.mfaService.BeginWebAuthn(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, opts)
+}
+
+// SendLoginCode emails a code for completing an MFA challenge with email OTP.
+func (h *Handler) SendLoginCode(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.SendLoginCode"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.ChallengeTokenRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.mfaService.SendLoginCode(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// SetupEmailOTP emails a code for enabling email OTP for the current user.
+func (h *Handler) SetupEmailOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.SetupEmailOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	locale := mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.mfaService.SetupEmailOTP(r.Context(), principal.UserID, locale); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ConfirmEmailOTP enables email OTP for the current user.
+func (h *Handler) ConfirmEmailOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.ConfirmEmailOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.CodeRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.ConfirmEmailOTP(r.Context(), principal.UserID, req.Code); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// DisableEmailOTP turns email OTP off for the current user.
+func (h *Handler) DisableEmailOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.DisableEmailOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.StepUpRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.DisableEmailOTP(r.Context(), principal.UserID, req.MFA); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// SendLoginSMS texts a code for completing an MFA challenge with SMS OTP.
+func (h *Handler) SendLoginSMS(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.SendLoginSMS"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.ChallengeTokenRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	req.IP = middleware.ClientIP(r)
+	if err := h.mfaService.SendLoginSMS(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// SetPhone sets the phone number of the current user and texts it a verification code.
+func (h *Handler) SetPhone(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.SetPhone"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.SetPhoneRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	req.IP = middleware.ClientIP(r)
+	if err := h.mfaService.SetPhone(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// VerifyPhone confirms the phone number of the current user.
+func (h *Handler) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.VerifyPhone"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.CodeRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.VerifyPhone(r.Context(), principal.UserID, req.Code); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// EnableSMSOTP turns SMS OTP on for the current user.
+func (h *Handler) EnableSMSOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.EnableSMSOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.EnableSMSOTP(r.Context(), principal.UserID); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// DisableSMSOTP turns SMS OTP off for the current user.
+func (h *Handler) DisableSMSOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.DisableSMSOTP"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.StepUpRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.mfaService.DisableSMSOTP(r.Context(), principal.UserID, req.MFA); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// BeginStepUp prepares a second factor check before a sensitive operation:
+// it sends an email or SMS code, or returns passkey options.
+func (h *Handler) BeginStepUp(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.BeginStepUp"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.BeginStepUpRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	req.IP = middleware.ClientIP(r)
+	opts, err := h.mfaService.BeginStepUp(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	if opts == nil {
+		httputils.SendOK(w, r, h.log, op)
+		return
+	}
+	httputils.SendDataOK(w, r, h.log, op, opts)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user.
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.RegenerateRecoveryCodes"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	codes, err := h.mfaService.RegenerateRecoveryCodes(r.Context(), principal.UserID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, codes)
+}
+
+// RecoveryCodesRemaining returns how many recovery codes the current user has left.
+func (h *Handler) RecoveryCodesRemaining(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.mfa.RecoveryCodesRemaining"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	n, err := h.mfaService.RecoveryCodesRemaining(r.Context(), principal.UserID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, map[string]int{"remaining": n})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// ActivityRecorder notes that a session was used.
+type ActivityRecorder interface {
+	RecordActivity(id, ip string, at time.Time)
+}
+
+// TrackActivity records the session of each authenticated request as active,
+// which keeps it from reaching its idle timeout. It must run after Authenticate.
+func TrackActivity(recorder ActivityRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := PrincipalFromContext(r.Context()); ok && p.SessionID != "" {
+				recorder.RecordActivity(p.SessionID, ClientIP(r), time.Now())
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/lib/jwt"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+)
+
+// Verifier validates access tokens.
+type Verifier interface {
+	Verify(token string) (*jwt.Claims, error)
+}
+
+// RevocationChecker reports whether an access token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error)
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID         int64
+	Scopes         []string
+	SessionID      string
+	TokenID        string
+	TokenExpiresAt time.Time
+	EmailVerified  bool
+	// AuthTime, AMR and ACR describe the last authentication of the session.
+	AuthTime time.Time
+	AMR      []string
+	ACR      string
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by Authenticate, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Authenticate verifies the request's access token, taken from the Authorization header or
+// the cookie named accessCookie, and stores the caller in the request context.
+// Requests without a valid, unrevoked token are rejected with 401.
+func Authenticate(verifier Verifier, revocations RevocationChecker, accessCookie string, log *slog.Logger) func(http.Handler) http.Handler {
+	const op = "delivery.http.middleware.Authenticate"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := tokenFromRequest(r, accessCookie)
+			if token == "" {
+				unauthorized(w, log, op, "", "missing access token")
+				return
+			}
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				msg := "invalid access token"
+				if errors.Is(err, jwt.ErrExpiredToken) {
+					msg = "access token expired"
+				}
+				log.Debug("access token rejected", slog.String("op", op), slog.String("err", err.Error()))
+				unauthorized(w, log, op, "invalid_token", msg)
+				return
+			}
+
+			userID, err := claims.UserID()
+			if err != nil {
+				unauthorized(w, log, op, "invalid_token", "invalid access token")
+				return
+			}
+
+			revoked, err := revocations.IsRevoked(r.Context(), claims.ID, claims.SessionID)
+			if err != nil {
+				httputils.WriteHTTPError(w, log, op, err)
+				return
+			}
+			if revoked {
+				unauthorized(w, log, op, "invalid_token", "access token revoked")
+				return
+			}
+
+			principal := &Principal{
+				UserID:         userID,
+				Scopes:         claims.Scopes(),
+				SessionID:      claims.SessionID,
+				TokenID:        claims.ID,
+				TokenExpiresAt: claims.ExpiresAt.Time,
+				EmailVerified:  claims.EmailVerified,
+				AMR:            claims.AMR,
+				ACR:            claims.ACR,
+			}
+			if claims.AuthTime != nil {
+				principal.AuthTime = claims.AuthTime.Time
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// tokenFromRequest returns the bearer token from the Authorization header or the auth cookie.
+func tokenFromRequest(r *http.Request, accessCookie string) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return ""
+		}
+		return strings.TrimSpace(token)
+	}
+
+	if c, err := r.Cookie(accessCookie); err == nil {
+		return c.Value
+	}
+
+	return ""
+}
+
+// unauthorized writes a 401 response with a RFC 6750 WWW-Authenticate challenge.
+func unauthorized(w http.ResponseWriter, log *slog.Logger, op, code, msg string) {
+	challenge := `Bearer realm="authx"`
+	if code != "" {
+		challenge += `, error="` + code + `", error_description="` + msg + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	httputils.WriteHTTPError(w, log, op, apperr.New(http.StatusUnauthorized, msg))
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/LullNil/authx-go/domain/session"
+)
+
+// ClientIP returns the IP address of the client. It relies on RealIP to have
+// set RemoteAddr when running behind a trusted proxy.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// ClientFromRequest describes the device a request comes from.
+func ClientFromRequest(r *http.Request) session.Client {
+	return session.Client{
+		UserAgent: r.UserAgent(),
+		IP:        ClientIP(r),
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/LullNil/authx-go/internal/delivery/http/authcookie"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+)
+
+// CSRF enforces double-submit CSRF tokens on state-changing requests authenticated by cookie.
+func CSRF(cookies *authcookie.Transport, log *slog.Logger) func(http.Handler) http.Handler {
+	const op = "delivery.http.middleware.CSRF"
+
+	return cookies.CSRF(func(w http.ResponseWriter, r *http.Request) {
+		httputils.WriteHTTPError(w, log, op, apperr.New(http.StatusForbidden, "invalid csrf token"))
+	})
+}
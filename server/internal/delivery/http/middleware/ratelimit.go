@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/LullNil/authx-go/internal/lib/ratelimit"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+)
+
+// RateLimit limits requests per client IP.
+func RateLimit(limiter *ratelimit.Limiter, log *slog.Logger) func(http.Handler) http.Handler {
+	const op = "delivery.http.middleware.RateLimit"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Allow(ClientIP(r)) {
+				httputils.WriteHTTPError(w, log, op, apperr.New(http.StatusTooManyRequests, "too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
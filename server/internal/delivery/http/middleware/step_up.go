@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/token"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+)
+
+// MethodLister returns the second factors a user can step up with.
+type MethodLister interface {
+	Methods(ctx context.Context, userID int64) ([]string, error)
+}
+
+// RequireStepUp lets a request through only if its session authenticated at
+// acr level within the last maxAge, e.g. "MFA within the last 5 minutes".
+// Users without a second factor can't reach a higher level, so a recent
+// authentication of any level is enough for them.
+// Others get a 401 with an RFC 9470 insufficient_user_authentication challenge,
+// and the error data lists the second factors the user can reauthenticate with.
+// It must run after Authenticate.
+func RequireStepUp(acr string, maxAge time.Duration, methods MethodLister, log *slog.Logger) func(http.Handler) http.Handler {
+	const op = "delivery.http.middleware.RequireStepUp"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			if !ok {
+				unauthorized(w, log, op, "", "unauthorized")
+				return
+			}
+
+			fresh := !principal.AuthTime.IsZero() && time.Since(principal.AuthTime) <= maxAge
+			if fresh && acrSatisfies(principal.ACR, acr) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userMethods, err := methods.Methods(r.Context(), principal.UserID)
+			if err != nil {
+				httputils.WriteHTTPError(w, log, op, err)
+				return
+			}
+			if fresh && len(userMethods) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			maxAgeSeconds := int(maxAge.Seconds())
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="authx", error="insufficient_user_authentication", error_description="step-up authentication required", acr_values="%s", max_age="%d"`,
+				acr, maxAgeSeconds,
+			))
+			httputils.WriteHTTPError(w, log, op, apperr.NewWithData(http.StatusUnauthorized, "step-up authentication required", mfa.StepUpChallenge{
+				Methods:   userMethods,
+				ACRValues: acr,
+				MaxAge:    maxAgeSeconds,
+			}))
+		})
+	}
+}
+
+// acrSatisfies reports whether a session at level have meets the required level.
+func acrSatisfies(have, want string) bool {
+	return want == token.ACRSingleFactor || have == want
+}
@@ -0,0 +1,148 @@
+package passkey
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/internal/delivery/http/middleware"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+	"github.com/go-chi/chi"
+)
+
+type Handler struct {
+	passkeyService passkey.Service
+	log            *slog.Logger
+}
+
+// New returns a new passkey handler.
+func New(passkeyService passkey.Service, log *slog.Logger) *Handler {
+	return &Handler{
+		passkeyService: passkeyService,
+		log:            log,
+	}
+}
+
+// BeginRegistration returns the options for creating a passkey for the current user.
+func (h *Handler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.passkey.BeginRegistration"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	opts, err := h.passkeyService.BeginRegistration(r.Context(), principal.UserID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, opts)
+}
+
+// FinishRegistration stores the passkey created by the browser.
+func (h *Handler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.passkey.FinishRegistration"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[passkey.FinishRegistrationRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	credential, err := h.passkeyService.FinishRegistration(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, credential)
+}
+
+// ListCredentials returns the passkeys of the current user.
+func (h *Handler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.passkey.ListCredentials"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	credentials, err := h.passkeyService.ListCredentials(r.Context(), principal.UserID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, credentials)
+}
+
+// DeleteCredential removes a passkey of the current user.
+func (h *Handler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.passkey.DeleteCredential"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Get id from path parameter
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusBadRequest, "id must be an integer"))
+		return
+	}
+
+	// Call service
+	if err := h.passkeyService.DeleteCredential(r.Context(), principal.UserID, id); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// BeginLogin returns the options for a username-less passkey login.
+func (h *Handler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.passkey.BeginLogin"
+
+	// Call service
+	opts, err := h.passkeyService.BeginLogin(r.Context())
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, opts)
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/LullNil/authx-go/domain/session"
+	"github.com/LullNil/authx-go/internal/delivery/http/authcookie"
+	"github.com/LullNil/authx-go/internal/delivery/http/middleware"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/LullNil/go-http-utils/httputils"
+	"github.com/go-chi/chi"
+)
+
+type Handler struct {
+	sessionService session.Service
+	cookies        *authcookie.Transport
+	log            *slog.Logger
+}
+
+// New returns a new session handler.
+func New(sessionService session.Service, cookies *authcookie.Transport, log *slog.Logger) *Handler {
+	return &Handler{
+		sessionService: sessionService,
+		cookies:        cookies,
+		log:            log,
+	}
+}
+
+// List returns the devices the current user is signed in on.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.session.List"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	sessions, err := h.sessionService.List(r.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendDataOK(w, r, h.log, op, sessions)
+}
+
+// Revoke signs the current user out of a device.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.session.Revoke"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Get id from path parameter
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusBadRequest, "missing session id"))
+		return
+	}
+
+	// Call service
+	err := h.sessionService.Revoke(r.Context(), session.RevokeRequest{
+		UserID:    principal.UserID,
+		SessionID: id,
+	})
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Signing out of this device also drops its cookies
+	if id == principal.SessionID {
+		h.cookies.Clear(w)
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
@@ -1,25 +1,39 @@
 package user
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
 	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/delivery/http/authcookie"
+	"github.com/LullNil/authx-go/internal/delivery/http/middleware"
+	"github.com/LullNil/authx-go/internal/lib/mailer"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
 
+	"github.com/LullNil/go-http-utils/apperr"
 	"github.com/LullNil/go-http-utils/httputils"
 	"github.com/go-playground/validator/v10"
 )
 
 type Handler struct {
 	userService user.Service
+	cookies     *authcookie.Transport
+	magicLink   config.MagicLink
 	log         *slog.Logger
 	validator   *validator.Validate
 }
 
 // New returns a new user handler.
-func New(userService user.Service, log *slog.Logger) *Handler {
+func New(userService user.Service, cookies *authcookie.Transport, magicLink config.MagicLink, log *slog.Logger) *Handler {
 	return &Handler{
 		userService: userService,
+		cookies:     cookies,
+		magicLink:   magicLink,
 		log:         log,
 		validator:   validator.New(),
 	}
@@ -41,16 +55,18 @@ func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
 	id, err := h.userService.RegisterUser(r.Context(), req)
 	if err != nil {
 		httputils.WriteHTTPError(w, h.log, op, err)
+		return
 	}
 
 	// Send successful response
 	httputils.SendDataOK(w, r, h.log, op, id)
 }
 
-// LoginUser authenticates user and returns JWT stub
+// LoginUser authenticates user and returns a new token pair.
 func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	const op = "delivery.http.user.LoginUser"
 
@@ -66,25 +82,482 @@ func (h *Handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
-	token, err := h.userService.LoginUser(r.Context(), req)
+	req.Client = middleware.ClientFromRequest(r)
+	resp, err := h.userService.LoginUser(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	h.sendTokens(w, r, op, resp)
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair.
+func (h *Handler) RefreshTokens(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.RefreshTokens"
+
+	// Take the refresh token from its cookie, or decode it from the body
+	req := user.RefreshRequest{RefreshToken: h.cookies.RefreshToken(r)}
+	if req.RefreshToken == "" {
+		var ok bool
+		req, ok = httputils.DecodeRequest[user.RefreshRequest](w, r, h.log, op)
+		if !ok {
+			return
+		}
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Client = middleware.ClientFromRequest(r)
+	resp, err := h.userService.RefreshTokens(r.Context(), req)
+	if err != nil {
+		h.cookies.Clear(w)
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	h.sendTokens(w, r, op, resp)
+}
+
+// RequestMagicLink emails a login link and binds it to this browser with a nonce cookie.
+// The response is the same whether or not the email is registered.
+func (h *Handler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.RequestMagicLink"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.MagicLinkRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	nonce, err := securetoken.New()
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Call service
+	req.Nonce = nonce
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.userService.RequestMagicLink(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	h.cookies.SetNonce(w, h.magicLink.NonceCookie, nonce, h.magicLink.NoncePath, time.Now().Add(h.magicLink.TokenTTL))
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// MagicLinkCallback exchanges a login link for a token pair.
+func (h *Handler) MagicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.MagicLinkCallback"
+
+	req := user.MagicLinkLoginRequest{
+		Token:  r.URL.Query().Get("token"),
+		Nonce:  h.cookies.Nonce(r, h.magicLink.NonceCookie),
+		Client: middleware.ClientFromRequest(r),
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	resp, err := h.userService.LoginWithMagicLink(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	h.cookies.ClearNonce(w, h.magicLink.NonceCookie, h.magicLink.NoncePath)
+
+	// Send successful response
+	h.sendTokens(w, r, op, resp)
+}
+
+// LoginWithPasskey finishes a username-less passkey login.
+func (h *Handler) LoginWithPasskey(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.LoginWithPasskey"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[passkey.FinishLoginRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Client = middleware.ClientFromRequest(r)
+	resp, err := h.userService.LoginWithPasskey(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	h.sendTokens(w, r, op, resp)
+}
+
+// CompleteMFALogin finishes a login with a second factor.
+func (h *Handler) CompleteMFALogin(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.CompleteMFALogin"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[mfa.CompleteChallengeRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Client = middleware.ClientFromRequest(r)
+	resp, err := h.userService.CompleteMFALogin(r.Context(), req)
 	if err != nil {
 		httputils.WriteHTTPError(w, h.log, op, err)
+		return
 	}
 
-	// TODO: send JWT in cookies
 	// Send successful response
-	httputils.SendDataOK(w, r, h.log, op, token)
+	h.sendTokens(w, r, op, resp)
+}
+
+// Reauthenticate checks the credentials of the current user again and
+// returns tokens for the same session with a fresh auth_time.
+func (h *Handler) Reauthenticate(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.Reauthenticate"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ReauthenticateRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	req.SessionID = principal.SessionID
+	resp, err := h.userService.Reauthenticate(r.Context(), req)
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	h.sendTokens(w, r, op, resp)
+}
+
+// sendTokens delivers a token pair through the configured transports,
+// or the MFA challenge when the login needs a second factor.
+func (h *Handler) sendTokens(w http.ResponseWriter, r *http.Request, op string, resp *user.LoginResponse) {
+	if resp.MFA != nil {
+		httputils.SendDataOK(w, r, h.log, op, resp.MFA)
+		return
+	}
+
+	if err := h.cookies.SetTokens(w, resp.AccessToken, resp.ExpiresAt, resp.RefreshToken, resp.RefreshExpiresAt); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	if !h.cookies.InBody() {
+		resp.AccessToken = ""
+		resp.RefreshToken = ""
+	}
+
+	httputils.SendDataOK(w, r, h.log, op, resp)
+}
+
+// LogoutUser ends the current session.
+func (h *Handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	h.logout(w, r, "delivery.http.user.LogoutUser", false)
+}
+
+// LogoutAll ends every session of the current user.
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	h.logout(w, r, "delivery.http.user.LogoutAll", true)
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request, op string, allSessions bool) {
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Call service
+	err := h.userService.LogoutUser(r.Context(), user.LogoutRequest{
+		UserID:         principal.UserID,
+		SessionID:      principal.SessionID,
+		TokenID:        principal.TokenID,
+		TokenExpiresAt: principal.TokenExpiresAt,
+		AllSessions:    allSessions,
+	})
+	if err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	h.cookies.Clear(w)
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ChangePassword changes the password of the current user.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.ChangePassword"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ChangePasswordRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	req.SessionID = principal.SessionID
+	if err := h.userService.ChangePassword(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ForgotPassword sends a password reset link. The response is the same whether or not the email is registered.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.ForgotPassword"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ForgotPasswordRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.userService.ForgotPassword(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ResetPassword sets a new password using a token from the reset email.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.ResetPassword"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ResetPasswordRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.userService.ResetPassword(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// VerifyEmail confirms the email address a verification link was sent to.
+// The token is read from the "token" query parameter on GET, so the link
+// can point here directly, or from the JSON body on POST.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.VerifyEmail"
+
+	// Take the token from the query, or decode it from the body
+	req := user.VerifyEmailRequest{Token: r.URL.Query().Get("token")}
+	if r.Method == http.MethodPost {
+		var ok bool
+		req, ok = httputils.DecodeRequest[user.VerifyEmailRequest](w, r, h.log, op)
+		if !ok {
+			return
+		}
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := h.userService.VerifyEmail(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ResendVerification sends a new verification link. The response is the same whether or not the email is registered.
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.ResendVerification"
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ResendVerificationRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.userService.ResendVerification(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ChangeEmail starts changing the email of the current user.
+func (h *Handler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.user.ChangeEmail"
+
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
+
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.ChangeEmailRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	req.UserID = principal.UserID
+	req.Locale = mailer.PreferredLocale(r.Header.Get("Accept-Language"))
+	if err := h.userService.ChangeEmail(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
+}
+
+// ConfirmEmailChange applies an email change using the token sent to the new address.
+func (h *Handler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.emailChangeToken(w, r, "delivery.http.user.ConfirmEmailChange", h.userService.ConfirmEmailChange)
+}
+
+// RevertEmailChange cancels or undoes an email change using the token sent to the old address.
+func (h *Handler) RevertEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.emailChangeToken(w, r, "delivery.http.user.RevertEmailChange", h.userService.RevertEmailChange)
+}
+
+func (h *Handler) emailChangeToken(w http.ResponseWriter, r *http.Request, op string, call func(context.Context, user.EmailChangeTokenRequest) error) {
+	// Decode request
+	req, ok := httputils.DecodeRequest[user.EmailChangeTokenRequest](w, r, h.log, op)
+	if !ok {
+		return
+	}
+
+	// Validate request
+	if !httputils.ValidateRequest(w, r, h.log, op, req) {
+		return
+	}
+
+	// Call service
+	if err := call(r.Context(), req); err != nil {
+		httputils.WriteHTTPError(w, h.log, op, err)
+		return
+	}
+
+	// Send successful response
+	httputils.SendOK(w, r, h.log, op)
 }
 
 // GetUserInfo retrieves user info from the database.
 func (h *Handler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "delivery.http.user.GetUserInfo"
 
-	// TODO: get user id from JWT
-	var uId int64 = 1
+	// Get caller from context
+	principal, ok := middleware.PrincipalFromContext(r.Context())
+	if !ok {
+		httputils.WriteHTTPError(w, h.log, op, apperr.New(http.StatusUnauthorized, "unauthorized"))
+		return
+	}
 
 	// Call service
-	user, err := h.userService.GetUserByID(r.Context(), uId)
+	user, err := h.userService.GetUserByID(r.Context(), principal.UserID)
 	if err != nil {
 		httputils.WriteHTTPError(w, h.log, op, err)
 		return
@@ -0,0 +1,52 @@
+package breach
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// Checker looks passwords up in a local Have I Been Pwned index, without
+// calling any external service.
+type Checker struct {
+	index *index
+}
+
+// Open opens an index built with BuildIndex.
+func Open(path string) (*Checker, error) {
+	ix, err := openIndex(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breach index %s: %w", path, err)
+	}
+	return &Checker{index: ix}, nil
+}
+
+// Count returns how many times the password appears in the breach corpus.
+func (c *Checker) Count(ctx context.Context, password string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.index.lookup(c.hash(password))
+}
+
+// Close releases the index file.
+func (c *Checker) Close() error {
+	return c.index.close()
+}
+
+func (c *Checker) hash(password string) []byte {
+	if c.index.kind == KindNTLM {
+		// NTLM is MD4 over the UTF-16LE encoding of the password.
+		h := md4.New()
+		for _, u := range utf16.Encode([]rune(password)) {
+			h.Write([]byte{byte(u), byte(u >> 8)})
+		}
+		return h.Sum(nil)
+	}
+
+	sum := sha1.Sum([]byte(password))
+	return sum[:]
+}
@@ -0,0 +1,203 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Index file layout:
+//
+//	magic    [8]byte  "AXHIBP1\n"
+//	kind     uint8    1 = SHA-1, 2 = NTLM
+//	fanout   [65537]uint64  offset of the first record whose hash starts with each 16-bit prefix
+//	records  sorted (hash, uint32 count) pairs
+//
+// Only the fanout table is kept in memory; records are binary searched on disk.
+const (
+	magic       = "AXHIBP1\n"
+	fanoutSize  = 1<<16 + 1
+	headerSize  = len(magic) + 1
+	recordsBase = int64(headerSize + fanoutSize*8)
+)
+
+const (
+	KindSHA1 = "sha1"
+	KindNTLM = "ntlm"
+)
+
+var kinds = map[string]byte{KindSHA1: 1, KindNTLM: 2}
+
+func hashLen(kind string) int {
+	if kind == KindNTLM {
+		return 16
+	}
+	return 20
+}
+
+// BuildIndex converts a Have I Been Pwned dump into an index file. The input
+// has one "HASH:COUNT" line per password ordered by hash, as produced by the
+// official downloader.
+func BuildIndex(in io.Reader, out io.WriteSeeker, kind string) (int64, error) {
+	code, ok := kinds[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown hash kind %q", kind)
+	}
+	size := hashLen(kind)
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	if _, err := w.WriteString(magic); err != nil {
+		return 0, err
+	}
+	if err := w.WriteByte(code); err != nil {
+		return 0, err
+	}
+	// Reserve space for the fanout table, written once all records are known.
+	if _, err := w.Write(make([]byte, fanoutSize*8)); err != nil {
+		return 0, err
+	}
+
+	var fanout [fanoutSize]uint64
+	var prev []byte
+	var n int64
+	record := make([]byte, size+4)
+
+	sc := bufio.NewScanner(in)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+
+		hexHash, countStr, ok := strings.Cut(text, ":")
+		if !ok {
+			return n, fmt.Errorf("line %d: expected HASH:COUNT", line)
+		}
+		hash, err := hex.DecodeString(hexHash)
+		if err != nil || len(hash) != size {
+			return n, fmt.Errorf("line %d: invalid %s hash", line, kind)
+		}
+		count, err := strconv.ParseUint(countStr, 10, 32)
+		if err != nil {
+			return n, fmt.Errorf("line %d: invalid count: %w", line, err)
+		}
+		if prev != nil && bytes.Compare(hash, prev) <= 0 {
+			return n, fmt.Errorf("line %d: input is not sorted by hash", line)
+		}
+		prev = append(prev[:0], hash...)
+
+		copy(record, hash)
+		binary.BigEndian.PutUint32(record[size:], uint32(count))
+		if _, err := w.Write(record); err != nil {
+			return n, err
+		}
+
+		// Every bucket after this record's prefix starts after it.
+		fanout[int(binary.BigEndian.Uint16(hash))+1] = uint64(n + 1)
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return n, err
+	}
+
+	// Fill empty buckets so every entry is the end of the previous bucket.
+	for i := 1; i < fanoutSize; i++ {
+		if fanout[i] < fanout[i-1] {
+			fanout[i] = fanout[i-1]
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return n, err
+	}
+	if _, err := out.Seek(int64(headerSize), io.SeekStart); err != nil {
+		return n, err
+	}
+	if err := binary.Write(out, binary.BigEndian, fanout[:]); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// index is an opened index file.
+type index struct {
+	file   *os.File
+	kind   string
+	size   int
+	fanout []uint64
+}
+
+func openIndex(path string) (*index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read index header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		f.Close()
+		return nil, errors.New("not a breach index file")
+	}
+
+	var kind string
+	for k, code := range kinds {
+		if code == header[len(magic)] {
+			kind = k
+		}
+	}
+	if kind == "" {
+		f.Close()
+		return nil, errors.New("unknown hash kind in index file")
+	}
+
+	fanout := make([]uint64, fanoutSize)
+	if err := binary.Read(f, binary.BigEndian, fanout); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read index fanout: %w", err)
+	}
+
+	return &index{file: f, kind: kind, size: hashLen(kind), fanout: fanout}, nil
+}
+
+// lookup returns the breach count of the hash, or 0 if it is not in the index.
+func (ix *index) lookup(hash []byte) (int, error) {
+	bucket := int(binary.BigEndian.Uint16(hash))
+	lo, hi := int64(ix.fanout[bucket]), int64(ix.fanout[bucket+1])
+
+	recSize := int64(ix.size + 4)
+	record := make([]byte, recSize)
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := ix.file.ReadAt(record, recordsBase+mid*recSize); err != nil {
+			return 0, err
+		}
+
+		switch cmp := bytes.Compare(record[:ix.size], hash); {
+		case cmp == 0:
+			return int(binary.BigEndian.Uint32(record[ix.size:])), nil
+		case cmp < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+func (ix *index) close() error {
+	return ix.file.Close()
+}
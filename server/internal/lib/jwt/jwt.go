@@ -0,0 +1,208 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims are the claims carried by authx access tokens.
+type Claims struct {
+	gojwt.RegisteredClaims
+	Scope         string `json:"scope,omitempty"`
+	SessionID     string `json:"sid,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	// AuthTime, AMR and ACR describe the last authentication of the session (OpenID Connect Core 1.0, section 2).
+	AuthTime *gojwt.NumericDate `json:"auth_time,omitempty"`
+	AMR      []string           `json:"amr,omitempty"`
+	ACR      string             `json:"acr,omitempty"`
+}
+
+// Scopes returns the space-delimited scope claim as a slice.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// UserID returns the subject parsed as a user ID.
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return id, nil
+}
+
+// Subject describes who an access token is issued to.
+type Subject struct {
+	UserID        int64
+	SessionID     string
+	Scopes        []string
+	EmailVerified bool
+	AuthTime      time.Time
+	AMR           []string
+	ACR           string
+}
+
+// SigningKeySource provides the key new tokens are signed with.
+type SigningKeySource interface {
+	SigningKey() *Key
+}
+
+// VerificationKeySource provides the keys tokens are verified with.
+type VerificationKeySource interface {
+	VerificationKey(kid string) (*Key, bool)
+}
+
+// Issuer mints signed access tokens.
+type Issuer struct {
+	keys     SigningKeySource
+	issuer   string
+	audience []string
+	ttl      time.Duration
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewIssuer returns a new token issuer signing with the current key of keys.
+func NewIssuer(cfg config.JWT, keys SigningKeySource) *Issuer {
+	return &Issuer{
+		keys:     keys,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.AccessTokenTTL,
+		leeway:   cfg.Leeway,
+		now:      time.Now,
+	}
+}
+
+// TTL returns the lifetime of issued tokens.
+func (i *Issuer) TTL() time.Duration {
+	return i.ttl
+}
+
+// Leeway returns the clock skew verifiers tolerate past a token's expiry.
+func (i *Issuer) Leeway() time.Duration {
+	return i.leeway
+}
+
+// Issue returns a signed access token for the given subject along with its claims.
+func (i *Issuer) Issue(sub Subject) (string, *Claims, error) {
+	const op = "lib.jwt.Issue"
+
+	jti, err := newID()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := i.now()
+	claims := &Claims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(sub.UserID, 10),
+			Issuer:    i.issuer,
+			Audience:  i.audience,
+			IssuedAt:  gojwt.NewNumericDate(now),
+			ExpiresAt: gojwt.NewNumericDate(now.Add(i.ttl)),
+			ID:        jti,
+		},
+		Scope:         strings.Join(sub.Scopes, " "),
+		SessionID:     sub.SessionID,
+		EmailVerified: sub.EmailVerified,
+		AMR:           sub.AMR,
+		ACR:           sub.ACR,
+	}
+	if !sub.AuthTime.IsZero() {
+		claims.AuthTime = gojwt.NewNumericDate(sub.AuthTime)
+	}
+
+	key := i.keys.SigningKey()
+	token := gojwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	signed, err := token.SignedString(key.Sign)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return signed, claims, nil
+}
+
+// Verifier validates access tokens issued by Issuer.
+type Verifier struct {
+	keys   VerificationKeySource
+	parser *gojwt.Parser
+}
+
+// NewVerifier returns a new token verifier resolving keys by their kid.
+func NewVerifier(cfg config.JWT, keys VerificationKeySource) *Verifier {
+	opts := []gojwt.ParserOption{
+		gojwt.WithIssuer(cfg.Issuer),
+		gojwt.WithLeeway(cfg.Leeway),
+		gojwt.WithIssuedAt(),
+		gojwt.WithExpirationRequired(),
+	}
+	if len(cfg.Audience) > 0 {
+		opts = append(opts, gojwt.WithAudience(cfg.Audience...))
+	}
+
+	return &Verifier{
+		keys:   keys,
+		parser: gojwt.NewParser(opts...),
+	}
+}
+
+// Verify parses the token, checks its signature and registered claims and returns its claims.
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	var claims Claims
+	_, err := v.parser.ParseWithClaims(tokenString, &claims, v.keyFunc)
+	if err != nil {
+		if errors.Is(err, gojwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.ID == "" || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &claims, nil
+}
+
+// keyFunc picks the verification key by kid and rejects tokens whose alg doesn't match it.
+func (v *Verifier) keyFunc(t *gojwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := v.keys.VerificationKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", t.Method.Alg(), kid)
+	}
+
+	return key.Verify, nil
+}
+
+// newID returns a random 128-bit hex identifier.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,191 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-that-is-at-least-32-bytes"
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	active := edDSAKey(t, "active", KeyStatusActive, nil)
+	recent := edDSAKey(t, "recent", KeyStatusRetired, timePtr(now.Add(-time.Minute)))
+	old := edDSAKey(t, "old", KeyStatusRetired, timePtr(now.Add(-time.Hour)))
+	hmacKey := config.SigningKey{ID: "hmac", Algorithm: AlgHS256, Status: KeyStatusStaged, Secret: testSecret}
+
+	cfg := testConfig(active, recent, old, hmacKey)
+	keys := newTestKeyManager(t, cfg)
+	verifier := NewVerifier(cfg, keys)
+
+	recentKey := mustLoad(t, recent)
+	oldKey := mustLoad(t, old)
+	activeKey := mustLoad(t, active)
+
+	// alg mismatch: an HS256 token whose kid names an EdDSA key, signed
+	// with that key's public key bytes as the HMAC secret
+	pub := []byte(activeKey.Verify.(ed25519.PublicKey))
+	confused := &Key{ID: "active", Method: gojwt.SigningMethodHS256, Sign: pub}
+
+	tests := []struct {
+		name    string
+		key     *Key
+		cfg     config.JWT
+		wantErr error
+	}{
+		{"active key", activeKey, cfg, nil},
+		{"staged key", mustLoad(t, hmacKey), cfg, nil},
+		{"retired key within retention", recentKey, cfg, nil},
+		{"retired key past retention", oldKey, cfg, ErrInvalidToken},
+		{"unknown kid", mustLoad(t, edDSAKey(t, "unknown", KeyStatusActive, nil)), cfg, ErrInvalidToken},
+		{"alg does not match kid", confused, cfg, ErrInvalidToken},
+		{"kid of another key", withID(recentKey, "active"), cfg, ErrInvalidToken},
+		{"wrong issuer", activeKey, withIssuer(cfg, "someone-else"), ErrInvalidToken},
+		{"expired", activeKey, withTTL(cfg, -time.Hour), ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := NewIssuer(tt.cfg, staticKey{tt.key}).Issue(Subject{UserID: 7, SessionID: "s1"})
+			if err != nil {
+				t.Fatalf("Issue: %v", err)
+			}
+
+			claims, err := verifier.Verify(token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if id, _ := claims.UserID(); id != 7 || claims.SessionID != "s1" {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestRetiredKeyExpiresWithoutReload(t *testing.T) {
+	now := time.Now()
+	active := edDSAKey(t, "active", KeyStatusActive, nil)
+	retired := edDSAKey(t, "retired", KeyStatusRetired, timePtr(now))
+
+	keys := newTestKeyManager(t, testConfig(active, retired))
+
+	if _, ok := keys.VerificationKey("retired"); !ok {
+		t.Fatal("retired key rejected within retention")
+	}
+
+	keys.now = func() time.Time { return now.Add(keys.retention) }
+	if _, ok := keys.VerificationKey("retired"); ok {
+		t.Error("retired key accepted after retention")
+	}
+	for _, k := range keys.PublicKeys() {
+		if k.ID == "retired" {
+			t.Error("retired key still published after retention")
+		}
+	}
+}
+
+func TestNoneAlgorithmIsRejected(t *testing.T) {
+	cfg := testConfig(edDSAKey(t, "active", KeyStatusActive, nil))
+	verifier := NewVerifier(cfg, newTestKeyManager(t, cfg))
+
+	now := time.Now()
+	token := gojwt.NewWithClaims(gojwt.SigningMethodNone, &Claims{RegisteredClaims: gojwt.RegisteredClaims{
+		Subject:   "7",
+		Issuer:    cfg.Issuer,
+		Audience:  cfg.Audience,
+		IssuedAt:  gojwt.NewNumericDate(now),
+		ExpiresAt: gojwt.NewNumericDate(now.Add(time.Minute)),
+		ID:        "jti",
+	}})
+	token.Header["kid"] = "active"
+	signed, err := token.SignedString(gojwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	if _, err := verifier.Verify(signed); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+type staticKey struct {
+	key *Key
+}
+
+func (k staticKey) SigningKey() *Key {
+	return k.key
+}
+
+func testConfig(keys ...config.SigningKey) config.JWT {
+	return config.JWT{
+		Issuer:         "authx",
+		Audience:       []string{"authx"},
+		AccessTokenTTL: 15 * time.Minute,
+		Leeway:         30 * time.Second,
+		Keys:           keys,
+	}
+}
+
+func newTestKeyManager(t *testing.T, cfg config.JWT) *KeyManager {
+	t.Helper()
+
+	m, err := NewKeyManager(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewKeyManager: %v", err)
+	}
+	return m
+}
+
+func edDSAKey(t *testing.T, id, status string, retiredAt *time.Time) config.SigningKey {
+	t.Helper()
+
+	pemData, err := GeneratePrivateKey(AlgEdDSA)
+	if err != nil {
+		t.Fatalf("GeneratePrivateKey: %v", err)
+	}
+	return config.SigningKey{ID: id, Algorithm: AlgEdDSA, Status: status, RetiredAt: retiredAt, PrivateKey: string(pemData)}
+}
+
+func mustLoad(t *testing.T, k config.SigningKey) *Key {
+	t.Helper()
+
+	key, err := LoadKey(k, "")
+	if err != nil {
+		t.Fatalf("LoadKey: %v", err)
+	}
+	return key
+}
+
+func withID(k *Key, id string) *Key {
+	c := *k
+	c.ID = id
+	return &c
+}
+
+func withIssuer(cfg config.JWT, issuer string) config.JWT {
+	cfg.Issuer = issuer
+	return cfg
+}
+
+func withTTL(cfg config.JWT, ttl time.Duration) config.JWT {
+	cfg.AccessTokenTTL = ttl
+	return cfg
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,284 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	KeyStatusStaged  = "staged"
+	KeyStatusActive  = "active"
+	KeyStatusRetired = "retired"
+)
+
+// Keyring is the on-disk format of the keyring file.
+type Keyring struct {
+	Keys []config.SigningKey `yaml:"keys"`
+}
+
+// ReadKeyring reads a keyring file.
+func ReadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var kr Keyring
+	if err := yaml.Unmarshal(data, &kr); err != nil {
+		return nil, fmt.Errorf("parse keyring %s: %w", path, err)
+	}
+
+	return &kr, nil
+}
+
+// WriteKeyring atomically replaces the keyring file.
+func WriteKeyring(path string, kr *Keyring) error {
+	data, err := yaml.Marshal(kr)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Active returns the active key of the keyring.
+func (kr *Keyring) Active() (*config.SigningKey, bool) {
+	for i := range kr.Keys {
+		if kr.Keys[i].Status == KeyStatusActive {
+			return &kr.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Find returns the key with the given ID.
+func (kr *Keyring) Find(id string) (*config.SigningKey, bool) {
+	for i := range kr.Keys {
+		if kr.Keys[i].ID == id {
+			return &kr.Keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// managedKey is a loaded keyring entry.
+type managedKey struct {
+	*Key
+	status    string
+	expiresAt time.Time // zero unless retired
+}
+
+// KeyManager holds every key in use: the active signing key, staged keys
+// published ahead of promotion and retired keys kept for verification until
+// the tokens they signed have expired.
+type KeyManager struct {
+	mu        sync.RWMutex
+	keys      map[string]*managedKey
+	active    *managedKey
+	retention time.Duration
+	cfg       config.JWT
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// NewKeyManager loads the keys configured in cfg.
+func NewKeyManager(cfg config.JWT, log *slog.Logger) (*KeyManager, error) {
+	m := &KeyManager{
+		cfg:       cfg,
+		retention: cfg.AccessTokenTTL + cfg.Leeway,
+		log:       log,
+		now:       time.Now,
+	}
+
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Reload re-reads the configured keys and the keyring file.
+// On error the previously loaded keys stay in place.
+func (m *KeyManager) Reload() error {
+	const op = "lib.jwt.KeyManager.Reload"
+
+	entries, baseDir, err := m.entries()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := m.now()
+	keys := make(map[string]*managedKey, len(entries))
+	var active *managedKey
+
+	for _, e := range entries {
+		if _, dup := keys[e.ID]; dup {
+			return fmt.Errorf("%s: duplicate key id %q", op, e.ID)
+		}
+
+		mk := &managedKey{status: e.Status}
+		switch e.Status {
+		case KeyStatusActive:
+			if active != nil {
+				return fmt.Errorf("%s: keys %q and %q are both active", op, active.ID, e.ID)
+			}
+		case KeyStatusStaged:
+		case KeyStatusRetired:
+			if e.RetiredAt != nil {
+				mk.expiresAt = e.RetiredAt.Add(m.retention)
+				if !now.Before(mk.expiresAt) {
+					continue
+				}
+			}
+		default:
+			return fmt.Errorf("%s: key %q has unknown status %q", op, e.ID, e.Status)
+		}
+
+		key, err := LoadKey(e, baseDir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		mk.Key = key
+		keys[e.ID] = mk
+
+		if e.Status == KeyStatusActive {
+			active = mk
+		}
+	}
+
+	if active == nil {
+		return fmt.Errorf("%s: no active signing key", op)
+	}
+	if len(keys) > 1 {
+		for id := range keys {
+			if id == "" {
+				return fmt.Errorf("%s: every key needs an id when more than one key is configured", op)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	changed := m.active == nil || m.active.ID != active.ID
+	m.keys = keys
+	m.active = active
+	m.mu.Unlock()
+
+	if changed {
+		m.log.Info("jwt signing key activated", slog.String("kid", active.ID), slog.String("alg", active.Method.Alg()))
+	}
+
+	return nil
+}
+
+// Run reloads the keyring file every interval until ctx is done.
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration) {
+	if m.cfg.KeyringPath == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reload(); err != nil {
+				m.log.Error("failed to reload jwt keyring", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// SigningKey returns the active signing key.
+func (m *KeyManager) SigningKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.active.Key
+}
+
+// VerificationKey returns the key with the given ID if it may still verify tokens.
+// An empty kid matches the active key.
+func (m *KeyManager) VerificationKey(kid string) (*Key, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if kid == "" {
+		return m.active.Key, true
+	}
+
+	mk, ok := m.keys[kid]
+	if !ok || m.expired(mk) {
+		return nil, false
+	}
+
+	return mk.Key, true
+}
+
+// PublicKeys returns the asymmetric keys that should be published, ordered by ID.
+// Shared secrets are never published.
+func (m *KeyManager) PublicKeys() []*Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []*Key
+	for _, mk := range m.keys {
+		if mk.Method.Alg() == AlgHS256 || m.expired(mk) {
+			continue
+		}
+		keys = append(keys, mk.Key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return keys
+}
+
+func (m *KeyManager) expired(mk *managedKey) bool {
+	return !mk.expiresAt.IsZero() && !m.now().Before(mk.expiresAt)
+}
+
+// entries collects the configured keys and the directory relative key paths are resolved against.
+func (m *KeyManager) entries() ([]config.SigningKey, string, error) {
+	if m.cfg.KeyringPath != "" {
+		kr, err := ReadKeyring(m.cfg.KeyringPath)
+		if err != nil {
+			return nil, "", err
+		}
+		return append(append([]config.SigningKey{}, m.cfg.Keys...), kr.Keys...), filepath.Dir(m.cfg.KeyringPath), nil
+	}
+
+	if len(m.cfg.Keys) > 0 {
+		return m.cfg.Keys, "", nil
+	}
+
+	if m.cfg.Secret == "" && m.cfg.PrivateKey == "" && m.cfg.PrivateKeyPath == "" {
+		return nil, "", errors.New("no jwt signing key configured")
+	}
+
+	return []config.SigningKey{{
+		ID:             m.cfg.KeyID,
+		Algorithm:      m.cfg.Algorithm,
+		Status:         KeyStatusActive,
+		Secret:         m.cfg.Secret,
+		PrivateKey:     m.cfg.PrivateKey,
+		PrivateKeyPath: m.cfg.PrivateKeyPath,
+	}}, "", nil
+}
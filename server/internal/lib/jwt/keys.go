@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	minSecretLength = 32
+	rsaKeyBits      = 2048
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key is a signing key together with the material needed to verify its signatures.
+type Key struct {
+	ID     string
+	Method gojwt.SigningMethod
+	Sign   any // []byte, *rsa.PrivateKey or ed25519.PrivateKey
+	Verify any // []byte, *rsa.PublicKey or ed25519.PublicKey
+}
+
+// LoadKey builds the key described by k. Relative private key paths are resolved against baseDir.
+func LoadKey(k config.SigningKey, baseDir string) (*Key, error) {
+	const op = "lib.jwt.LoadKey"
+
+	switch k.Algorithm {
+	case AlgHS256:
+		if len(k.Secret) < minSecretLength {
+			return nil, fmt.Errorf("%s: key %q: HS256 secret must be at least %d bytes", op, k.ID, minSecretLength)
+		}
+		secret := []byte(k.Secret)
+		return &Key{ID: k.ID, Method: gojwt.SigningMethodHS256, Sign: secret, Verify: secret}, nil
+
+	case AlgRS256, AlgEdDSA:
+		path := k.PrivateKeyPath
+		if path != "" && baseDir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := readPEM(k.PrivateKey, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", op, k.ID, err)
+		}
+		key, err := ParsePrivateKey(k.Algorithm, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", op, k.ID, err)
+		}
+		key.ID = k.ID
+		return key, nil
+
+	default:
+		return nil, fmt.Errorf("%s: key %q: %w: %q", op, k.ID, ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+}
+
+// ParsePrivateKey parses a PEM-encoded PKCS#8 (or PKCS#1 for RSA) private key for the given algorithm.
+func ParsePrivateKey(alg string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+
+	var parsed any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	switch alg {
+	case AlgRS256:
+		pk, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("RS256 requires an RSA private key")
+		}
+		return &Key{Method: gojwt.SigningMethodRS256, Sign: pk, Verify: &pk.PublicKey}, nil
+	case AlgEdDSA:
+		pk, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("EdDSA requires an Ed25519 private key")
+		}
+		return &Key{Method: gojwt.SigningMethodEdDSA, Sign: pk, Verify: pk.Public().(crypto.PublicKey)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// GeneratePrivateKey returns a new PEM-encoded PKCS#8 private key for an asymmetric algorithm.
+func GeneratePrivateKey(alg string) ([]byte, error) {
+	var pk any
+	var err error
+	switch alg {
+	case AlgRS256:
+		pk, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, pk, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// readPEM returns inline PEM data if set, otherwise the contents of path.
+func readPEM(inline, path string) ([]byte, error) {
+	if s := strings.TrimSpace(inline); s != "" {
+		// Env vars often carry PEM with escaped newlines.
+		return []byte(strings.ReplaceAll(s, `\n`, "\n")), nil
+	}
+	if path == "" {
+		return nil, errors.New("private key is not configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return data, nil
+}
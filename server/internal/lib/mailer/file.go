@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+)
+
+type fileMailer struct {
+	dir  string
+	from *mail.Address
+}
+
+// NewFile returns a Mailer that writes every message as an .eml file to dir.
+// It is meant for local development and tests.
+func NewFile(dir string, from *mail.Address) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("mailer: create mail dir: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+func (m *fileMailer) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+
+	raw, err := encode(m.from, msg, now)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+
+	suffix, err := securetoken.NewN(6)
+	if err != nil {
+		return fmt.Errorf("mailer: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), suffix)
+	if err := os.WriteFile(filepath.Join(m.dir, name), raw, 0o600); err != nil {
+		return fmt.Errorf("mailer: write message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+)
+
+type logMailer struct {
+	log *slog.Logger
+}
+
+// NewLog returns a Mailer that writes messages to the log instead of sending them.
+// It is meant for local development only: message bodies may contain secrets.
+func NewLog(log *slog.Logger) Mailer {
+	return &logMailer{log: log}
+}
+
+func (m *logMailer) Send(_ context.Context, msg Message) error {
+	m.log.Info("mail",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("text", msg.Text),
+	)
+	return nil
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+
+	"github.com/LullNil/authx-go/config"
+)
+
+// Transports.
+const (
+	TransportLog  = "log"
+	TransportFile = "file"
+	TransportSMTP = "smtp"
+)
+
+// Message is an outgoing email.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Mailer selected by cfg.Transport.
+func New(cfg config.Mail, log *slog.Logger) (Mailer, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid from address %q: %w", cfg.From, err)
+	}
+
+	switch cfg.Transport {
+	case TransportLog, "":
+		return NewLog(log), nil
+	case TransportFile:
+		return NewFile(cfg.File.Dir, from)
+	case TransportSMTP:
+		return NewSMTP(cfg.SMTP, from)
+	default:
+		return nil, fmt.Errorf("mailer: unknown transport %q", cfg.Transport)
+	}
+}
@@ -0,0 +1,171 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// Templates renders messages from a text template and an optional HTML
+// template per locale: <locale>/<name>.txt and <locale>/<name>.html.
+// The text template must define a "subject" template.
+type Templates struct {
+	fsys          []fs.FS
+	defaultLocale string
+
+	mu    sync.Mutex
+	cache map[string]*compiled
+}
+
+type compiled struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// NewTemplates returns templates that look files up in dir first, when it
+// is not empty, and fall back to the built-in ones.
+func NewTemplates(dir, defaultLocale string) (*Templates, error) {
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := []fs.FS{builtin}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("mailer: templates dir: %w", err)
+		}
+		fsys = []fs.FS{os.DirFS(dir), builtin}
+	}
+
+	return &Templates{
+		fsys:          fsys,
+		defaultLocale: defaultLocale,
+		cache:         make(map[string]*compiled),
+	}, nil
+}
+
+// Render executes the named template in the best matching locale and returns
+// a message without recipient. The locale falls back from "pt-BR" to "pt" and
+// then to the default locale.
+func (t *Templates) Render(name, locale string, data any) (Message, error) {
+	var (
+		tmpl *compiled
+		err  error
+	)
+	for _, l := range t.candidates(locale) {
+		tmpl, err = t.load(l, name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("mailer: template %q: %w", name, err)
+	}
+
+	var subject, text bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("mailer: template %q: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("mailer: template %q: %w", name, err)
+	}
+
+	msg := Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+	}
+
+	if tmpl.html != nil {
+		var html bytes.Buffer
+		if err := tmpl.html.Execute(&html, data); err != nil {
+			return Message{}, fmt.Errorf("mailer: template %q: %w", name, err)
+		}
+		msg.HTML = html.String()
+	}
+
+	return msg, nil
+}
+
+func (t *Templates) candidates(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+
+	var out []string
+	if locale != "" && fs.ValidPath(locale) {
+		out = append(out, locale)
+		if base, _, ok := strings.Cut(locale, "-"); ok {
+			out = append(out, base)
+		}
+	}
+	return append(out, t.defaultLocale)
+}
+
+func (t *Templates) load(locale, name string) (*compiled, error) {
+	key := locale + "/" + name
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c, ok := t.cache[key]; ok {
+		return c, nil
+	}
+
+	text, err := t.read(path.Join(locale, name+".txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compiled{}
+	if c.text, err = texttemplate.New(name).Parse(text); err != nil {
+		return nil, err
+	}
+
+	html, err := t.read(path.Join(locale, name+".html"))
+	switch {
+	case err == nil:
+		if c.html, err = htmltemplate.New(name).Parse(html); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	t.cache[key] = c
+	return c, nil
+}
+
+// read returns the first file found in the override and built-in filesystems.
+func (t *Templates) read(name string) (string, error) {
+	for _, fsys := range t.fsys {
+		b, err := fs.ReadFile(fsys, name)
+		if err == nil {
+			return string(b), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", fs.ErrNotExist
+}
+
+// PreferredLocale returns the first language tag of an Accept-Language header.
+func PreferredLocale(acceptLanguage string) string {
+	first, _, _ := strings.Cut(acceptLanguage, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,252 @@
+package password
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// The verifiers in this file only check hashes imported from other systems;
+// passwords are never hashed with them.
+
+type djangoPBKDF2 struct{}
+
+// NewDjangoPBKDF2 verifies Django's pbkdf2_sha256$<iterations>$<salt>$<base64 hash> hashes.
+func NewDjangoPBKDF2() Verifier {
+	return djangoPBKDF2{}
+}
+
+func (djangoPBKDF2) ID() string {
+	return "pbkdf2_sha256"
+}
+
+func (djangoPBKDF2) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "pbkdf2_sha256$")
+}
+
+func (djangoPBKDF2) Verify(password, encoded string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return errors.New("malformed pbkdf2_sha256 hash")
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return errors.New("malformed pbkdf2_sha256 iterations")
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return fmt.Errorf("malformed pbkdf2_sha256 hash: %w", err)
+	}
+
+	got, err := pbkdf2.Key(sha256.New, password, []byte(parts[2]), iterations, len(want))
+	if err != nil {
+		return err
+	}
+
+	return compare(want, got)
+}
+
+type djangoSalted struct{}
+
+// NewDjangoSalted verifies Django's legacy salted digests, sha1$<salt>$<hex>
+// and md5$<salt>$<hex>, where the digest is taken over salt+password.
+func NewDjangoSalted() Verifier {
+	return djangoSalted{}
+}
+
+func (djangoSalted) ID() string {
+	return "salted_digest"
+}
+
+func (djangoSalted) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "sha1$") || strings.HasPrefix(encoded, "md5$")
+}
+
+func (djangoSalted) Verify(password, encoded string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 3 {
+		return errors.New("malformed salted digest")
+	}
+
+	want, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed salted digest: %w", err)
+	}
+
+	var got []byte
+	switch parts[0] {
+	case "sha1":
+		sum := sha1.Sum([]byte(parts[1] + password))
+		got = sum[:]
+	case "md5":
+		sum := md5.Sum([]byte(parts[1] + password))
+		got = sum[:]
+	}
+
+	return compare(want, got)
+}
+
+const phpassItoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+type phpass struct{}
+
+// NewPHPass verifies phpass portable hashes ($P$ / $H$), the iterated, salted
+// MD5 scheme used by WordPress, phpBB and many older PHP applications.
+func NewPHPass() Verifier {
+	return phpass{}
+}
+
+func (phpass) ID() string {
+	return "phpass"
+}
+
+func (phpass) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$P$") || strings.HasPrefix(encoded, "$H$")
+}
+
+func (phpass) Verify(password, encoded string) error {
+	if len(encoded) != 34 {
+		return errors.New("malformed phpass hash")
+	}
+
+	countLog2 := strings.IndexByte(phpassItoa64, encoded[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return errors.New("malformed phpass iteration count")
+	}
+	salt := encoded[4:12]
+
+	sum := md5.Sum([]byte(salt + password))
+	for range 1 << countLog2 {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+
+	got := encoded[:12] + phpassEncode64(sum[:])
+	return compare([]byte(encoded), []byte(got))
+}
+
+// phpassEncode64 is phpass' own base64 variant.
+func phpassEncode64(input []byte) string {
+	var b strings.Builder
+	count := len(input)
+
+	for i := 0; i < count; {
+		value := int(input[i])
+		i++
+		b.WriteByte(phpassItoa64[value&0x3f])
+		if i < count {
+			value |= int(input[i]) << 8
+		}
+		b.WriteByte(phpassItoa64[(value>>6)&0x3f])
+		if i >= count {
+			break
+		}
+		i++
+		if i < count {
+			value |= int(input[i]) << 16
+		}
+		b.WriteByte(phpassItoa64[(value>>12)&0x3f])
+		if i >= count {
+			break
+		}
+		i++
+		b.WriteByte(phpassItoa64[(value>>18)&0x3f])
+	}
+
+	return b.String()
+}
+
+type firebaseScrypt struct {
+	signerKey     []byte
+	saltSeparator []byte
+	rounds        int
+	memCost       int
+}
+
+// NewFirebaseScrypt verifies Firebase's modified scrypt hashes, stored as
+// $firebase-scrypt$<base64 salt>$<base64 hash>, using the project's hash parameters.
+func NewFirebaseScrypt(cfg config.FirebaseScrypt) (Verifier, error) {
+	signerKey, err := base64.StdEncoding.DecodeString(cfg.SignerKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firebase signer key: %w", err)
+	}
+	saltSeparator, err := base64.StdEncoding.DecodeString(cfg.SaltSeparator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firebase salt separator: %w", err)
+	}
+	if cfg.MemCost < 1 || cfg.MemCost > 30 || cfg.Rounds < 1 {
+		return nil, errors.New("invalid firebase scrypt cost parameters")
+	}
+
+	return &firebaseScrypt{
+		signerKey:     signerKey,
+		saltSeparator: saltSeparator,
+		rounds:        cfg.Rounds,
+		memCost:       cfg.MemCost,
+	}, nil
+}
+
+// EncodeFirebaseScrypt builds the stored form of a hash and salt exported from Firebase.
+func EncodeFirebaseScrypt(hash, salt string) string {
+	return "$firebase-scrypt$" + salt + "$" + hash
+}
+
+func (f *firebaseScrypt) ID() string {
+	return "firebase-scrypt"
+}
+
+func (f *firebaseScrypt) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$firebase-scrypt$")
+}
+
+func (f *firebaseScrypt) Verify(password, encoded string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 {
+		return errors.New("malformed firebase-scrypt hash")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed firebase-scrypt salt: %w", err)
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return fmt.Errorf("malformed firebase-scrypt hash: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(password), append(salt, f.saltSeparator...), 1<<f.memCost, f.rounds, 1, 32)
+	if err != nil {
+		return err
+	}
+
+	// The derived key encrypts the project's signer key with AES-256-CTR and a zero IV.
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	got := make([]byte, len(f.signerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(got, f.signerKey)
+
+	return compare(want, got)
+}
+
+func compare(want, got []byte) error {
+	if subtle.ConstantTimeCompare(want, got) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrMismatch      = errors.New("password does not match")
+	ErrUnknownFormat = errors.New("unknown password hash format")
+)
+
+// Verifier checks passwords against hashes of one algorithm.
+type Verifier interface {
+	// ID returns the algorithm identifier, e.g. "argon2id".
+	ID() string
+	// Recognizes reports whether the encoded hash was produced by this algorithm.
+	Recognizes(encoded string) bool
+	// Verify returns ErrMismatch if the password does not match the encoded hash.
+	Verify(password, encoded string) error
+}
+
+// Parser is implemented by verifiers that can check an encoded hash is well
+// formed without a password, so bad hashes are caught when they are imported
+// rather than on the user's first login.
+type Parser interface {
+	Parse(encoded string) error
+}
+
+// Hasher hashes passwords into self-describing strings (PHC string format
+// or, for bcrypt, its modular crypt format) that carry the algorithm and parameters.
+type Hasher interface {
+	Verifier
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the encoded hash was produced with parameters other than the current ones.
+	NeedsRehash(encoded string) bool
+}
+
+// Manager hashes new passwords with the preferred hasher and verifies
+// existing hashes with whichever registered verifier recognizes them.
+type Manager struct {
+	preferred Hasher
+	verifiers []Verifier
+	peppers   *Peppers
+}
+
+// NewManager returns a manager hashing with preferred. Additional verifiers,
+// such as legacy algorithms of imported users, are only used for verification.
+func NewManager(preferred Hasher, others ...Verifier) *Manager {
+	return &Manager{
+		preferred: preferred,
+		verifiers: append([]Verifier{preferred}, others...),
+	}
+}
+
+// WithPeppers makes the manager pepper new hashes with the current pepper
+// and verify peppered hashes of any known version.
+func (m *Manager) WithPeppers(peppers *Peppers) *Manager {
+	m.peppers = peppers
+	return m
+}
+
+// Hash hashes the password with the preferred algorithm and the current pepper.
+func (m *Manager) Hash(password string) (string, error) {
+	version := m.peppers.Current()
+	if version == 0 {
+		return m.preferred.Hash(password)
+	}
+
+	peppered, err := m.peppers.Apply(version, password)
+	if err != nil {
+		return "", err
+	}
+
+	inner, err := m.preferred.Hash(peppered)
+	if err != nil {
+		return "", err
+	}
+
+	return wrapPeppered(version, inner), nil
+}
+
+// Verify checks the password against the encoded hash. It returns
+// ErrMismatch on a wrong password, and on success reports whether the hash
+// should be replaced because it uses another algorithm, outdated parameters
+// or an old (or no) pepper.
+func (m *Manager) Verify(password, encoded string) (needsRehash bool, err error) {
+	version, inner, err := unwrapPeppered(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := m.verifierFor(inner)
+	if err != nil {
+		return false, err
+	}
+
+	if version != 0 {
+		if password, err = m.peppers.Apply(version, password); err != nil {
+			return false, err
+		}
+	}
+
+	if err := v.Verify(password, inner); err != nil {
+		return false, err
+	}
+
+	if version != m.peppers.Current() || v.ID() != m.preferred.ID() {
+		return true, nil
+	}
+	return m.preferred.NeedsRehash(inner), nil
+}
+
+// Check returns an error describing why the encoded hash cannot be verified:
+// an unknown format or pepper version, or a malformed hash.
+func (m *Manager) Check(encoded string) error {
+	version, inner, err := unwrapPeppered(encoded)
+	if err != nil {
+		return err
+	}
+	if version != 0 && !m.peppers.Has(version) {
+		return fmt.Errorf("unknown pepper version %d", version)
+	}
+
+	v, err := m.verifierFor(inner)
+	if err != nil {
+		return err
+	}
+	if p, ok := v.(Parser); ok {
+		return p.Parse(inner)
+	}
+	return nil
+}
+
+func (m *Manager) verifierFor(encoded string) (Verifier, error) {
+	for _, v := range m.verifiers {
+		if v.Recognizes(encoded) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, prefix(encoded))
+}
+
+// prefix returns the algorithm part of an encoded hash for error messages without leaking the hash.
+func prefix(encoded string) string {
+	if !strings.HasPrefix(encoded, "$") {
+		return ""
+	}
+	id, _, _ := strings.Cut(encoded[1:], "$")
+	return id
+}
+
+// New returns a manager hashing with the algorithm selected in cfg and able
+// to verify hashes of every supported algorithm.
+func New(cfg config.Password) (*Manager, error) {
+	const op = "lib.password.New"
+
+	if cfg.Argon2id.Parallelism == 0 || cfg.Argon2id.Parallelism > 255 {
+		return nil, fmt.Errorf("%s: argon2id parallelism must be between 1 and 255", op)
+	}
+	if cfg.Bcrypt.Cost < bcrypt.MinCost || cfg.Bcrypt.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("%s: bcrypt cost must be between %d and %d", op, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	argon := NewArgon2id(Argon2idParams{
+		Memory:      cfg.Argon2id.Memory,
+		Iterations:  cfg.Argon2id.Iterations,
+		Parallelism: uint8(cfg.Argon2id.Parallelism),
+		SaltLength:  cfg.Argon2id.SaltLength,
+		KeyLength:   cfg.Argon2id.KeyLength,
+	})
+	bc := NewBcrypt(cfg.Bcrypt.Cost)
+
+	legacy, err := legacyVerifiers(cfg.Legacy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	peppers, err := NewPeppers(cfg.Pepper)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var m *Manager
+	switch cfg.Algorithm {
+	case argon.ID():
+		m = NewManager(argon, append([]Verifier{bc}, legacy...)...)
+	case bc.ID():
+		m = NewManager(bc, append([]Verifier{argon}, legacy...)...)
+	default:
+		return nil, fmt.Errorf("%s: unsupported password algorithm %q", op, cfg.Algorithm)
+	}
+
+	return m.WithPeppers(peppers), nil
+}
+
+// legacyVerifiers returns the verifiers for hashes imported from other identity systems.
+func legacyVerifiers(cfg config.LegacyPassword) ([]Verifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	verifiers := []Verifier{
+		NewDjangoPBKDF2(),
+		NewDjangoSalted(),
+		NewPHPass(),
+	}
+
+	if cfg.FirebaseScrypt.SignerKey != "" {
+		fb, err := NewFirebaseScrypt(cfg.FirebaseScrypt)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, fb)
+	}
+
+	return verifiers, nil
+}
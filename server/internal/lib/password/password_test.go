@@ -0,0 +1,217 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LullNil/authx-go/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testPassword = "correct horse battery staple"
+	testPepperV1 = "pepper-version-one-at-least-32-bytes"
+	testPepperV2 = "pepper-version-two-at-least-32-bytes"
+)
+
+// Hash parameters and a known hash exported from a Firebase project.
+var testFirebase = config.FirebaseScrypt{
+	SignerKey:     "jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA==",
+	SaltSeparator: "Bw==",
+	Rounds:        8,
+	MemCost:       14,
+}
+
+func TestVerify(t *testing.T) {
+	m := newTestManager(t, testConfig())
+
+	argon, err := m.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	weakArgon, err := NewArgon2id(Argon2idParams{Memory: 32, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcryptHash, err := NewBcrypt(bcrypt.MinCost).Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		password    string
+		encoded     string
+		needsRehash bool
+		wantErr     error
+	}{
+		{"argon2id with current params", testPassword, argon, false, nil},
+		{"argon2id with old params", testPassword, weakArgon, true, nil},
+		{"bcrypt", testPassword, bcryptHash, true, nil},
+		{"django pbkdf2", "secret", "pbkdf2_sha256$1000$salt$qN+JnzxPIE2WfgrWPAkph8EAVeuwF7PZ0ordIY1Peq0=", true, nil},
+		{"django sha1", "secret", "sha1$abc$de0a408ef519cd62e7379039634152874895c50c", true, nil},
+		{"django md5", "secret", "md5$abc$33e7cb694fb6fb2f848af6774d9ff138", true, nil},
+		{"phpass", "test12345", "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", true, nil},
+		{"firebase scrypt", "user1password", EncodeFirebaseScrypt("lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ==", "42xEC+ixf3L2lw=="), true, nil},
+
+		{"argon2id wrong password", "wrong", argon, false, ErrMismatch},
+		{"bcrypt wrong password", "wrong", bcryptHash, false, ErrMismatch},
+		{"django pbkdf2 wrong password", "wrong", "pbkdf2_sha256$1000$salt$qN+JnzxPIE2WfgrWPAkph8EAVeuwF7PZ0ordIY1Peq0=", false, ErrMismatch},
+		{"django sha1 wrong password", "wrong", "sha1$abc$de0a408ef519cd62e7379039634152874895c50c", false, ErrMismatch},
+		{"phpass wrong password", "wrong", "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", false, ErrMismatch},
+		{"unknown format", testPassword, "$unknown$abc", false, ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := m.Verify(tt.password, tt.encoded)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if needsRehash != tt.needsRehash {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestLegacyHashesRejectedWhenDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Legacy.Enabled = false
+	m := newTestManager(t, cfg)
+
+	_, err := m.Verify("secret", "sha1$abc$de0a408ef519cd62e7379039634152874895c50c")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Verify = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestBcryptCostChangeNeedsRehash(t *testing.T) {
+	cfg := testConfig()
+	cfg.Algorithm = "bcrypt"
+	cfg.Bcrypt.Cost = bcrypt.MinCost + 1
+	m := newTestManager(t, cfg)
+
+	current, err := m.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	cheaper, err := NewBcrypt(bcrypt.MinCost).Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	for _, tt := range []struct {
+		encoded string
+		want    bool
+	}{
+		{current, false},
+		{cheaper, true},
+	} {
+		needsRehash, err := m.Verify(testPassword, tt.encoded)
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if needsRehash != tt.want {
+			t.Errorf("needsRehash = %v, want %v for cost %s", needsRehash, tt.want, tt.encoded[4:6])
+		}
+	}
+}
+
+func TestPepperVersions(t *testing.T) {
+	unpeppered := newTestManager(t, testConfig())
+	v1 := newTestManager(t, withPepper(testConfig(), config.Pepper{Version: 1, Secret: testPepperV1}))
+	v2 := newTestManager(t, withPepper(testConfig(), config.Pepper{
+		Version:  2,
+		Secret:   testPepperV2,
+		Previous: []config.PepperKey{{Version: 1, Secret: testPepperV1}},
+	}))
+
+	plainHash := mustHash(t, unpeppered)
+	v1Hash := mustHash(t, v1)
+	v2Hash := mustHash(t, v2)
+
+	if !strings.HasPrefix(v1Hash, "$pepper$v=1$") || !strings.HasPrefix(v2Hash, "$pepper$v=2$") {
+		t.Fatalf("hashes don't record their pepper version: %q, %q", v1Hash, v2Hash)
+	}
+
+	tests := []struct {
+		name        string
+		m           *Manager
+		encoded     string
+		needsRehash bool
+		wantErr     error
+	}{
+		{"current version", v2, v2Hash, false, nil},
+		{"previous version", v2, v1Hash, true, nil},
+		{"no pepper", v2, plainHash, true, nil},
+		{"unknown version", v1, v2Hash, false, ErrUnknownPepper},
+		{"pepper disabled", unpeppered, v1Hash, false, ErrUnknownPepper},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := tt.m.Verify(testPassword, tt.encoded)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if needsRehash != tt.needsRehash {
+				t.Errorf("needsRehash = %v, want %v", needsRehash, tt.needsRehash)
+			}
+		})
+	}
+
+	// the pepper is part of the hashed input
+	stripped := strings.TrimPrefix(v2Hash, "$pepper$v=2")
+	if _, err := v2.Verify(testPassword, stripped); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify without the pepper prefix = %v, want %v", err, ErrMismatch)
+	}
+}
+
+func testConfig() config.Password {
+	return config.Password{
+		Algorithm: "argon2id",
+		Argon2id:  config.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		Bcrypt:    config.Bcrypt{Cost: bcrypt.MinCost},
+		Legacy:    config.LegacyPassword{Enabled: true, FirebaseScrypt: testFirebase},
+	}
+}
+
+func withPepper(cfg config.Password, pepper config.Pepper) config.Password {
+	cfg.Pepper = pepper
+	return cfg
+}
+
+func newTestManager(t *testing.T, cfg config.Password) *Manager {
+	t.Helper()
+
+	m, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func mustHash(t *testing.T, m *Manager) string {
+	t.Helper()
+
+	encoded, err := m.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	return encoded
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+)
+
+const pepperPrefix = "$pepper$v="
+
+var ErrUnknownPepper = errors.New("unknown pepper version")
+
+// Peppers holds the server-side HMAC keys mixed into passwords before hashing.
+// Hashes record the version of the pepper they were made with, so old
+// versions stay usable for verification after a rotation.
+type Peppers struct {
+	current int
+	keys    map[int][]byte
+}
+
+// NewPeppers loads the current and previous peppers. A zero current version disables peppering.
+func NewPeppers(cfg config.Pepper) (*Peppers, error) {
+	p := &Peppers{
+		current: cfg.Version,
+		keys:    make(map[int][]byte),
+	}
+
+	all := append([]config.PepperKey{{Version: cfg.Version, Secret: cfg.Secret, SecretPath: cfg.SecretPath}}, cfg.Previous...)
+	for _, k := range all {
+		if k.Version == 0 {
+			continue
+		}
+		if k.Version < 0 {
+			return nil, fmt.Errorf("invalid pepper version %d", k.Version)
+		}
+		if _, dup := p.keys[k.Version]; dup {
+			return nil, fmt.Errorf("duplicate pepper version %d", k.Version)
+		}
+
+		secret, err := readSecret(k.Secret, k.SecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("pepper version %d: %w", k.Version, err)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("pepper version %d must be at least 32 bytes", k.Version)
+		}
+		p.keys[k.Version] = secret
+	}
+
+	return p, nil
+}
+
+// Current returns the version new hashes are peppered with, or 0 if peppering is disabled.
+func (p *Peppers) Current() int {
+	if p == nil {
+		return 0
+	}
+	return p.current
+}
+
+// Has reports whether the pepper of the given version is known.
+func (p *Peppers) Has(version int) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.keys[version]
+	return ok
+}
+
+// Apply mixes the pepper of the given version into the password.
+// The result is base64 so it stays within bcrypt's 72-byte input limit.
+func (p *Peppers) Apply(version int, password string) (string, error) {
+	if !p.Has(version) {
+		return "", fmt.Errorf("%w: %d", ErrUnknownPepper, version)
+	}
+	key := p.keys[version]
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// wrapPeppered records the pepper version in front of an inner encoded hash.
+func wrapPeppered(version int, inner string) string {
+	return pepperPrefix + strconv.Itoa(version) + inner
+}
+
+// unwrapPeppered splits a peppered hash into its pepper version and inner hash.
+// Hashes without a pepper have version 0.
+func unwrapPeppered(encoded string) (int, string, error) {
+	if !strings.HasPrefix(encoded, pepperPrefix) {
+		return 0, encoded, nil
+	}
+
+	rest := encoded[len(pepperPrefix):]
+	i := strings.IndexByte(rest, '$')
+	if i <= 0 {
+		return 0, "", errors.New("malformed peppered hash")
+	}
+
+	version, err := strconv.Atoi(rest[:i])
+	if err != nil || version <= 0 {
+		return 0, "", errors.New("malformed pepper version")
+	}
+
+	return version, rest[i:], nil
+}
+
+// readSecret returns inline secret data if set, otherwise the trimmed contents of path.
+func readSecret(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path == "" {
+		return nil, errors.New("secret is not configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSpace(string(data))), nil
+}
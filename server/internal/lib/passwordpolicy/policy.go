@@ -0,0 +1,234 @@
+package passwordpolicy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/internal/lib/breach"
+
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// bcryptMaxBytes is the longest input bcrypt takes into account; longer passwords are rejected by it.
+const bcryptMaxBytes = 72
+
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeNoUpper       = "missing_upper"
+	CodeNoLower       = "missing_lower"
+	CodeNoDigit       = "missing_digit"
+	CodeNoSymbol      = "missing_symbol"
+	CodeTooFewClasses = "too_few_character_classes"
+	CodeContainsUser  = "contains_user_info"
+	CodeTooWeak       = "too_weak"
+	CodeBreached      = "breached"
+)
+
+const (
+	BreachModeReject = "reject"
+	BreachModeWarn   = "warn"
+)
+
+// BreachChecker reports how often a password appears in known breaches.
+type BreachChecker interface {
+	Count(ctx context.Context, password string) (int, error)
+	Close() error
+}
+
+// Violation is a single rule a password breaks.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Policy validates passwords against the configured rules.
+type Policy struct {
+	cfg       config.PasswordPolicy
+	maxBytes  int
+	breach    BreachChecker
+	breachCfg config.BreachCheck
+	log       *slog.Logger
+}
+
+// New returns a policy for the given password settings. When passwords are
+// hashed with bcrypt without a pepper, the maximum length is capped at 72 bytes.
+// If a breach index is configured it is opened and consulted on every check.
+func New(cfg config.Password, log *slog.Logger) (*Policy, error) {
+	p := cfg.Policy
+	if p.MinLength < 1 {
+		return nil, fmt.Errorf("password policy: min_length must be positive")
+	}
+	if p.MaxLength < p.MinLength {
+		return nil, fmt.Errorf("password policy: max_length must not be less than min_length")
+	}
+	if p.MinScore < 0 || p.MinScore > 4 {
+		return nil, fmt.Errorf("password policy: min_score must be between 0 and 4")
+	}
+
+	maxBytes := 0
+	if cfg.Algorithm == "bcrypt" && cfg.Pepper.Version == 0 {
+		maxBytes = bcryptMaxBytes
+	}
+
+	policy := &Policy{cfg: p, maxBytes: maxBytes, breachCfg: cfg.Breach, log: log}
+
+	if cfg.Breach.IndexPath != "" {
+		if cfg.Breach.Mode != BreachModeReject && cfg.Breach.Mode != BreachModeWarn {
+			return nil, fmt.Errorf("password policy: unknown breach mode %q", cfg.Breach.Mode)
+		}
+		checker, err := breach.Open(cfg.Breach.IndexPath)
+		if err != nil {
+			return nil, fmt.Errorf("password policy: %w", err)
+		}
+		policy.breach = checker
+	}
+
+	return policy, nil
+}
+
+// Close releases the breach index, if any.
+func (p *Policy) Close() error {
+	if p.breach == nil {
+		return nil
+	}
+	return p.breach.Close()
+}
+
+// Check returns every rule the password violates. userInputs (username,
+// email, ...) must not appear in the password and weaken its strength score.
+func (p *Policy) Check(ctx context.Context, password string, userInputs ...string) ([]Violation, error) {
+	var violations []Violation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.cfg.MinLength {
+		add(CodeTooShort, "password must be at least %d characters long", p.cfg.MinLength)
+	}
+	if length > p.cfg.MaxLength {
+		add(CodeTooLong, "password must be at most %d characters long", p.cfg.MaxLength)
+	} else if p.maxBytes > 0 && len(password) > p.maxBytes {
+		add(CodeTooLong, "password must be at most %d bytes long", p.maxBytes)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !upper {
+		add(CodeNoUpper, "password must contain an uppercase letter")
+	}
+	if p.cfg.RequireLower && !lower {
+		add(CodeNoLower, "password must contain a lowercase letter")
+	}
+	if p.cfg.RequireDigit && !digit {
+		add(CodeNoDigit, "password must contain a digit")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		add(CodeNoSymbol, "password must contain a symbol")
+	}
+	if classes := count(upper, lower, digit, symbol); classes < p.cfg.MinCharClasses {
+		add(CodeTooFewClasses, "password must contain at least %d of: uppercase, lowercase, digits, symbols", p.cfg.MinCharClasses)
+	}
+
+	inputs := expandUserInputs(userInputs)
+	if p.cfg.DisallowUserInfo && containsAny(password, inputs) {
+		add(CodeContainsUser, "password must not contain your username or email")
+	}
+
+	if p.cfg.MinScore > 0 {
+		// zxcvbn is expensive on very long inputs; the length rules already reject those.
+		if length <= p.cfg.MaxLength {
+			if score := zxcvbn.PasswordStrength(password, inputs).Score; score < p.cfg.MinScore {
+				add(CodeTooWeak, "password is too easy to guess")
+			}
+		}
+	}
+
+	if p.breach != nil {
+		breached, err := p.breached(ctx, password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			add(CodeBreached, "password has appeared in a data breach, please choose another one")
+		}
+	}
+
+	return violations, nil
+}
+
+// breached looks the password up in the breach index. In warn mode a hit is
+// only logged and the password is accepted.
+func (p *Policy) breached(ctx context.Context, password string) (bool, error) {
+	const op = "lib.passwordpolicy.breached"
+
+	count, err := p.breach.Count(ctx, password)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if count < p.breachCfg.MinCount || count == 0 {
+		return false, nil
+	}
+
+	if p.breachCfg.Mode == BreachModeWarn {
+		p.log.Warn("accepted password found in breach corpus", slog.String("op", op), slog.Int("count", count))
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// expandUserInputs lowercases the inputs and adds the local part of email addresses.
+func expandUserInputs(inputs []string) []string {
+	var out []string
+	for _, in := range inputs {
+		in = strings.ToLower(strings.TrimSpace(in))
+		if in == "" {
+			continue
+		}
+		out = append(out, in)
+		if local, _, ok := strings.Cut(in, "@"); ok && local != "" {
+			out = append(out, local)
+		}
+	}
+	return out
+}
+
+// containsAny reports whether the password contains one of the inputs, ignoring case.
+// Inputs shorter than three characters are ignored to avoid spurious matches.
+func containsAny(password string, inputs []string) bool {
+	lower := strings.ToLower(password)
+	for _, in := range inputs {
+		if utf8.RuneCountInString(in) >= 3 && strings.Contains(lower, in) {
+			return true
+		}
+	}
+	return false
+}
+
+func count(flags ...bool) int {
+	n := 0
+	for _, f := range flags {
+		if f {
+			n++
+		}
+	}
+	return n
+}
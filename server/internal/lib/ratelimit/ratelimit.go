@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/lib/lru"
+)
+
+// Limiter allows at most limit events per key in fixed windows. State is kept
+// in memory, so limits apply per instance.
+type Limiter struct {
+	mu      sync.Mutex
+	windows *lru.Cache[string, *window]
+	limit   int
+	period  time.Duration
+}
+
+type window struct {
+	count int
+}
+
+// New returns a limiter tracking at most size keys.
+func New(limit int, period time.Duration, size int) *Limiter {
+	return &Limiter{
+		windows: lru.New[string, *window](size),
+		limit:   limit,
+		period:  period,
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// A non-positive limit or period disables limiting.
+func (l *Limiter) Allow(key string) bool {
+	if l.limit <= 0 || l.period <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows.Get(key)
+	if !ok {
+		l.windows.Set(key, &window{count: 1}, l.period)
+		return true
+	}
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -0,0 +1,85 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of AES-256 keys.
+const KeySize = 32
+
+var ErrDecrypt = errors.New("secretbox: decryption failed")
+
+// Box encrypts small secrets at rest with AES-256-GCM and authenticates
+// values that are only ever compared, never decrypted.
+type Box struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+// New returns a box using key, which must be KeySize bytes.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key must be %d bytes", KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The MAC key is derived so the same key is never used for both purposes.
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("secretbox mac"))
+
+	return &Box{aead: aead, macKey: mac.Sum(nil)}, nil
+}
+
+// NewFromBase64 returns a box using a standard base64-encoded key.
+func NewFromBase64(key string) (*Box, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: invalid key encoding: %w", err)
+	}
+	return New(raw)
+}
+
+// Seal encrypts plaintext. additionalData, e.g. the owner's ID, must be passed to Open unchanged.
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a ciphertext produced by Seal.
+func (b *Box) Open(ciphertext, additionalData []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := b.aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// MAC returns an HMAC-SHA256 of message. Unlike a plain digest it can't be
+// brute-forced offline without the key, so it suits low-entropy values.
+func (b *Box) MAC(message []byte) []byte {
+	mac := hmac.New(sha256.New, b.macKey)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
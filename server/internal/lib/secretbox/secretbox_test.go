@@ -0,0 +1,82 @@
+package secretbox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box := newTestBox(t, 1)
+	ad := []byte("user:42")
+
+	sealed, err := box.Seal([]byte("secret"), ad)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	again, err := box.Seal([]byte("secret"), ad)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+
+	plain, err := box.Open(sealed, ad)
+	if err != nil || string(plain) != "secret" {
+		t.Fatalf("Open = %q, %v", plain, err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name       string
+		box        *Box
+		ciphertext []byte
+		ad         []byte
+	}{
+		{"other additional data", box, sealed, []byte("user:7")},
+		{"other key", newTestBox(t, 2), sealed, ad},
+		{"tampered", box, tampered, ad},
+		{"truncated", box, sealed[:4], ad},
+	}
+	for _, tt := range tests {
+		if _, err := tt.box.Open(tt.ciphertext, tt.ad); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: Open = %v, want %v", tt.name, err, ErrDecrypt)
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	box := newTestBox(t, 1)
+
+	if !bytes.Equal(box.MAC([]byte("a")), box.MAC([]byte("a"))) {
+		t.Error("MAC is not deterministic")
+	}
+	if bytes.Equal(box.MAC([]byte("a")), box.MAC([]byte("b"))) {
+		t.Error("different messages have the same MAC")
+	}
+	if bytes.Equal(box.MAC([]byte("a")), newTestBox(t, 2).MAC([]byte("a"))) {
+		t.Error("different keys give the same MAC")
+	}
+}
+
+func TestNewRejectsShortKeys(t *testing.T) {
+	if _, err := New(make([]byte, KeySize-1)); err == nil {
+		t.Error("New accepted a short key")
+	}
+	if _, err := NewFromBase64("not base64!"); err == nil {
+		t.Error("NewFromBase64 accepted an invalid encoding")
+	}
+}
+
+func newTestBox(t *testing.T, fill byte) *Box {
+	t.Helper()
+
+	box, err := New(bytes.Repeat([]byte{fill}, KeySize))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return box
+}
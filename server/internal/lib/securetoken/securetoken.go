@@ -0,0 +1,38 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// DefaultLength is the number of random bytes in tokens created by New.
+const DefaultLength = 32
+
+// New returns a random URL-safe token with DefaultLength bytes of entropy.
+func New() (string, error) {
+	return NewN(DefaultLength)
+}
+
+// NewN returns a random URL-safe token with n bytes of entropy.
+func NewN(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex-encoded SHA-256 digest of token, suitable for storage and lookup.
+// High-entropy tokens don't need a slow password hash.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Equal compares two tokens in constant time.
+func Equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
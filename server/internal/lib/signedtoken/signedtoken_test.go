@@ -0,0 +1,83 @@
+package signedtoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret-that-is-at-least-32-bytes"
+
+func TestVerify(t *testing.T) {
+	signer := newTestSigner(t, testSecret)
+	now := signer.now()
+
+	token, err := signer.Sign("verify_email", 42, "alice@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	payload, sig, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"p":"verify_email","s":1,"v":"alice@example.com","e":9999999999}`))
+	flipped := []byte(sig)
+	flipped[0] ^= 1
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		token   string
+		purpose string
+		at      time.Time
+		wantErr error
+	}{
+		{"valid", signer, token, "verify_email", now, nil},
+		{"just before expiry", signer, token, "verify_email", now.Add(time.Hour - time.Second), nil},
+		{"at expiry", signer, token, "verify_email", now.Add(time.Hour), ErrExpiredToken},
+		{"other purpose", signer, token, "reset_password", now, ErrInvalidToken},
+		{"other secret", newTestSigner(t, testSecret+"!"), token, "verify_email", now, ErrInvalidToken},
+		{"tampered payload", signer, forged + "." + sig, "verify_email", now, ErrInvalidToken},
+		{"tampered signature", signer, payload + "." + string(flipped), "verify_email", now, ErrInvalidToken},
+		{"missing signature", signer, payload, "verify_email", now, ErrInvalidToken},
+		{"garbage", signer, "not.a-token", "verify_email", now, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.signer.now = func() time.Time { return tt.at }
+
+			c, err := tt.signer.Verify(tt.token, tt.purpose)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if c.Subject != 42 || c.Value != "alice@example.com" {
+				t.Errorf("unexpected claims %+v", c)
+			}
+		})
+	}
+}
+
+func TestNewRejectsShortSecrets(t *testing.T) {
+	if _, err := New(testSecret[:MinSecretLength-1]); err == nil {
+		t.Error("New accepted a short secret")
+	}
+}
+
+func newTestSigner(t *testing.T, secret string) *Signer {
+	t.Helper()
+
+	s, err := New(secret)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+	return s
+}
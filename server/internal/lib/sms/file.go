@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a Sender that appends every message as a JSON line to path.
+// It is meant for local development and tests.
+func NewFile(path string) (Sender, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("sms: create dir: %w", err)
+	}
+	return &fileSender{path: path}, nil
+}
+
+func (s *fileSender) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		To   string    `json:"to"`
+		Body string    `json:"body"`
+	}{time.Now().UTC(), msg.To, msg.Body})
+	if err != nil {
+		return fmt.Errorf("sms: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("sms: open file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("sms: write message: %w", err)
+	}
+
+	return nil
+}
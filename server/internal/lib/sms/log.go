@@ -0,0 +1,24 @@
+package sms
+
+import (
+	"context"
+	"log/slog"
+)
+
+type logSender struct {
+	log *slog.Logger
+}
+
+// NewLog returns a Sender that writes messages to the log instead of sending them.
+// It is meant for local development only: messages contain one-time codes.
+func NewLog(log *slog.Logger) Sender {
+	return &logSender{log: log}
+}
+
+func (s *logSender) Send(_ context.Context, msg Message) error {
+	s.log.Info("sms",
+		slog.String("to", msg.To),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,41 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/LullNil/authx-go/config"
+)
+
+// Transports.
+const (
+	TransportLog    = "log"
+	TransportFile   = "file"
+	TransportTwilio = "twilio"
+)
+
+// Message is an outgoing text message. To is an E.164 number.
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender delivers text messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the Sender selected by cfg.Transport.
+func New(cfg config.SMS, log *slog.Logger) (Sender, error) {
+	switch cfg.Transport {
+	case TransportLog, "":
+		return NewLog(log), nil
+	case TransportFile:
+		return NewFile(cfg.File.Path)
+	case TransportTwilio:
+		return NewTwilio(cfg.Twilio, cfg.From)
+	default:
+		return nil, fmt.Errorf("sms: unknown transport %q", cfg.Transport)
+	}
+}
@@ -0,0 +1,84 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/LullNil/authx-go/config"
+)
+
+type twilioSender struct {
+	endpoint string
+	sid      string
+	token    string
+	from     string
+	client   *http.Client
+}
+
+// NewTwilio returns a Sender that posts messages to the Twilio Messages API,
+// or any service compatible with it at cfg.BaseURL.
+func NewTwilio(cfg config.Twilio, from string) (Sender, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" {
+		return nil, errors.New("sms: twilio account sid and auth token are required")
+	}
+	if from == "" {
+		return nil, errors.New("sms: from number is required")
+	}
+
+	endpoint, err := url.JoinPath(cfg.BaseURL, "2010-04-01", "Accounts", cfg.AccountSID, "Messages.json")
+	if err != nil {
+		return nil, fmt.Errorf("sms: invalid twilio base url: %w", err)
+	}
+
+	return &twilioSender{
+		endpoint: endpoint,
+		sid:      cfg.AccountSID,
+		token:    cfg.AuthToken,
+		from:     from,
+		client:   &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (s *twilioSender) Send(ctx context.Context, msg Message) error {
+	form := url.Values{
+		"To":   {msg.To},
+		"From": {s.from},
+		"Body": {msg.Body},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("sms: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.sid, s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("sms: send: %s: %d %s", resp.Status, apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("sms: send: %s", resp.Status)
+	}
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret of the RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := Code(rfcSecret, Step(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	codeAt := func(offset int64) string { return Code(rfcSecret, current+offset) }
+
+	tests := []struct {
+		name   string
+		code   string
+		skew   int
+		wantOK bool
+	}{
+		{"current step, skew 0", codeAt(0), 0, true},
+		{"previous step, skew 0", codeAt(-1), 0, false},
+		{"next step, skew 0", codeAt(1), 0, false},
+		{"previous step, skew 1", codeAt(-1), 1, true},
+		{"next step, skew 1", codeAt(1), 1, true},
+		{"two steps back, skew 1", codeAt(-2), 1, false},
+		{"two steps ahead, skew 1", codeAt(2), 1, false},
+		{"two steps back, skew 2", codeAt(-2), 2, true},
+		{"spaces are ignored", " " + codeAt(0)[:3] + " " + codeAt(0)[3:], 0, true},
+		{"too short", codeAt(0)[:5], 1, false},
+		{"too long", codeAt(0) + "0", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(rfcSecret, tt.code, now, tt.skew); ok != tt.wantOK {
+				t.Errorf("Validate = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateReturnsMatchingStep(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	want := Step(now) - 1
+
+	step, ok := Validate(rfcSecret, Code(rfcSecret, want), now, 1)
+	if !ok || step != want {
+		t.Errorf("Validate = %d, %v, want %d, true", step, ok, want)
+	}
+}
@@ -0,0 +1,106 @@
+// Package useragent derives a human-readable browser, operating system and
+// device from a User-Agent header, for listing a user's sessions. It only
+// knows common browsers and is not meant for feature detection.
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Device types.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Info is what could be told from a User-Agent. Unknown fields are empty.
+type Info struct {
+	// Browser is the name and major version, e.g. "Firefox 128".
+	Browser string
+	// OS is the operating system, e.g. "Android 14" or "macOS".
+	OS string
+	// Device is one of the Device constants.
+	Device string
+}
+
+// browsers are tried in order: many user agents also claim to be Chrome or Safari.
+var browsers = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/(\d+)`)},
+	{"Yandex Browser", regexp.MustCompile(`YaBrowser/(\d+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+)[.\d]* (?:Mobile/\S+ )?Safari/`)},
+	{"curl", regexp.MustCompile(`^curl/(\d+)`)},
+}
+
+var (
+	androidRe = regexp.MustCompile(`Android (\d+)`)
+	iOSRe     = regexp.MustCompile(`OS (\d+)[_\d]* like Mac OS X`)
+	botRe     = regexp.MustCompile(`(?i)bot|crawler|spider|slurp|headless`)
+)
+
+// Parse returns what can be told from ua.
+func Parse(ua string) Info {
+	var info Info
+	if ua == "" {
+		info.Device = DeviceUnknown
+		return info
+	}
+
+	for _, b := range browsers {
+		if m := b.re.FindStringSubmatch(ua); m != nil {
+			info.Browser = b.name + " " + m[1]
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad"):
+		info.OS, info.Device = iOS("iPadOS", ua), DeviceTablet
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPod"):
+		info.OS, info.Device = iOS("iOS", ua), DeviceMobile
+	case strings.Contains(ua, "Android"):
+		info.OS = "Android"
+		if m := androidRe.FindStringSubmatch(ua); m != nil {
+			info.OS += " " + m[1]
+		}
+		// Android tablets leave "Mobile" out of the user agent
+		info.Device = DeviceTablet
+		if strings.Contains(ua, "Mobile") {
+			info.Device = DeviceMobile
+		}
+	case strings.Contains(ua, "Windows"):
+		info.OS, info.Device = "Windows", DeviceDesktop
+	case strings.Contains(ua, "Macintosh"):
+		info.OS, info.Device = "macOS", DeviceDesktop
+	case strings.Contains(ua, "CrOS"):
+		info.OS, info.Device = "ChromeOS", DeviceDesktop
+	case strings.Contains(ua, "Linux"):
+		info.OS, info.Device = "Linux", DeviceDesktop
+	default:
+		info.Device = DeviceUnknown
+	}
+
+	if botRe.MatchString(ua) {
+		info.Device = DeviceBot
+	}
+
+	return info
+}
+
+// iOS returns name with the major version from an iOS user agent.
+func iOS(name, ua string) string {
+	if m := iOSRe.FindStringSubmatch(ua); m != nil {
+		return name + " " + m[1]
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/internal/lib/lru"
+)
+
+type denylist struct {
+	next        token.Denylist
+	entries     *lru.Cache[string, bool]
+	negativeTTL time.Duration
+	now         func() time.Time
+}
+
+// NewDenylist wraps a denylist with an in-memory LRU cache.
+// Revoked keys are cached until the token they refer to expires; keys found
+// not to be revoked are cached for negativeTTL, which bounds how long a
+// revocation made by another instance can go unnoticed.
+func NewDenylist(next token.Denylist, size int, negativeTTL time.Duration) token.Denylist {
+	return &denylist{
+		next:        next,
+		entries:     lru.New[string, bool](size),
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+	}
+}
+
+// Add stores the key in the underlying denylist and caches it.
+func (d *denylist) Add(ctx context.Context, key string, expiresAt time.Time) error {
+	if err := d.next.Add(ctx, key, expiresAt); err != nil {
+		return err
+	}
+
+	d.entries.Set(key, true, expiresAt.Sub(d.now()))
+	return nil
+}
+
+// Contains answers from the cache when every key is known and falls back to the underlying denylist otherwise.
+func (d *denylist) Contains(ctx context.Context, keys ...string) (bool, error) {
+	var missing []string
+	for _, key := range keys {
+		revoked, ok := d.entries.Get(key)
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	revoked, err := d.next.Contains(ctx, missing...)
+	if err != nil {
+		return false, err
+	}
+
+	// A positive answer can't be attributed to a single key, so only negatives are cached.
+	if !revoked {
+		for _, key := range missing {
+			d.entries.Set(key, false, d.negativeTTL)
+		}
+	}
+
+	return revoked, nil
+}
+
+// DeleteExpired purges the underlying denylist; expired cache entries are evicted lazily.
+func (d *denylist) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return d.next.DeleteExpired(ctx, before)
+}
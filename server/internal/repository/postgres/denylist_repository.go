@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type denylistRepo struct {
+	db *sql.DB
+}
+
+// NewDenylistRepository creates a new revoked access token repository.
+func NewDenylistRepository(db *sql.DB) *denylistRepo {
+	return &denylistRepo{
+		db: db,
+	}
+}
+
+// Add stores a revoked key until expiresAt.
+func (r *denylistRepo) Add(ctx context.Context, key string, expiresAt time.Time) error {
+	const op = "repository.postgres.denylist.Add"
+
+	query := `
+		INSERT INTO revoked_tokens (key, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = GREATEST(revoked_tokens.expires_at, EXCLUDED.expires_at)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, key, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Contains reports whether any of the keys is revoked and not yet expired.
+func (r *denylistRepo) Contains(ctx context.Context, keys ...string) (bool, error) {
+	const op = "repository.postgres.denylist.Contains"
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM revoked_tokens
+			WHERE key = ANY($1) AND expires_at > NOW()
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, pq.Array(keys)).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return exists, nil
+}
+
+// DeleteExpired removes entries that expired before the given time.
+func (r *denylistRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const op = "repository.postgres.denylist.DeleteExpired"
+
+	query := `
+		DELETE FROM revoked_tokens
+		WHERE expires_at <= $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
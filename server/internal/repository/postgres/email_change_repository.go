@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+type emailChangeRepo struct {
+	db *sql.DB
+}
+
+// NewEmailChangeRepository creates a new email change repository.
+func NewEmailChangeRepository(db *sql.DB) *emailChangeRepo {
+	return &emailChangeRepo{
+		db: db,
+	}
+}
+
+// Save stores a new email change.
+func (r *emailChangeRepo) Save(ctx context.Context, c *user.EmailChange) (int64, error) {
+	const op = "repository.postgres.emailChange.Save"
+
+	query := `
+		INSERT INTO email_changes (
+			user_id, old_email, new_email, confirm_token_hash, revert_token_hash,
+			created_at, confirm_expires_at, revert_expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		c.UserID,
+		c.OldEmail,
+		c.NewEmail,
+		c.ConfirmTokenHash,
+		c.RevertTokenHash,
+		c.CreatedAt,
+		c.ConfirmExpiresAt,
+		c.RevertExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetByConfirmHash retrieves an email change by the hash of its confirmation token.
+func (r *emailChangeRepo) GetByConfirmHash(ctx context.Context, hash string) (*user.EmailChange, error) {
+	const op = "repository.postgres.emailChange.GetByConfirmHash"
+
+	c, err := r.get(ctx, "confirm_token_hash", hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// GetByRevertHash retrieves an email change by the hash of its revert token.
+func (r *emailChangeRepo) GetByRevertHash(ctx context.Context, hash string) (*user.EmailChange, error) {
+	const op = "repository.postgres.emailChange.GetByRevertHash"
+
+	c, err := r.get(ctx, "revert_token_hash", hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// DeletePending removes changes of the user that were neither confirmed nor reverted.
+func (r *emailChangeRepo) DeletePending(ctx context.Context, userID int64) error {
+	const op = "repository.postgres.emailChange.DeletePending"
+
+	query := `
+		DELETE FROM email_changes
+		WHERE user_id = $1 AND confirmed_at IS NULL AND reverted_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Confirm applies the change to the user in a single transaction.
+func (r *emailChangeRepo) Confirm(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.emailChange.Confirm"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE email_changes
+		SET confirmed_at = $2
+		WHERE id = $1 AND confirmed_at IS NULL AND reverted_at IS NULL
+		RETURNING user_id, old_email, new_email
+	`
+
+	var userID int64
+	var oldEmail, newEmail string
+	if err := tx.QueryRowContext(ctx, query, id, at).Scan(&userID, &oldEmail, &newEmail); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setUserEmail(ctx, tx, userID, oldEmail, newEmail, at); err != nil {
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrConflict) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Revert cancels the change, restoring the old address if it was already applied.
+func (r *emailChangeRepo) Revert(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.emailChange.Revert"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE email_changes
+		SET reverted_at = $2
+		WHERE id = $1 AND reverted_at IS NULL
+		RETURNING user_id, old_email, new_email, confirmed_at
+	`
+
+	var userID int64
+	var oldEmail, newEmail string
+	var confirmedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx, query, id, at).Scan(&userID, &oldEmail, &newEmail, &confirmedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The old address was verified, since the revert link was sent to it.
+	if confirmedAt.Valid {
+		err := setUserEmail(ctx, tx, userID, newEmail, oldEmail, at)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			if errors.Is(err, repository.ErrConflict) {
+				return err
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *emailChangeRepo) get(ctx context.Context, column, hash string) (*user.EmailChange, error) {
+	query := fmt.Sprintf(`
+		SELECT id, user_id, old_email, new_email, confirm_token_hash, revert_token_hash,
+			created_at, confirm_expires_at, revert_expires_at, confirmed_at, reverted_at
+		FROM email_changes
+		WHERE %s = $1
+	`, column)
+
+	var c user.EmailChange
+	var confirmedAt, revertedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.OldEmail,
+		&c.NewEmail,
+		&c.ConfirmTokenHash,
+		&c.RevertTokenHash,
+		&c.CreatedAt,
+		&c.ConfirmExpiresAt,
+		&c.RevertExpiresAt,
+		&confirmedAt,
+		&revertedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		c.ConfirmedAt = &confirmedAt.Time
+	}
+	if revertedAt.Valid {
+		c.RevertedAt = &revertedAt.Time
+	}
+
+	return &c, nil
+}
+
+// setUserEmail replaces the user's email if it still equals from, marking the new one as verified.
+func setUserEmail(ctx context.Context, tx *sql.Tx, userID int64, from, to string, verifiedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET email = $3, email_verified_at = $4
+		WHERE id = $1 AND email = $2
+	`
+
+	res, err := tx.ExecContext(ctx, query, userID, from, to, verifiedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { // unique_violation
+				return repository.ErrConflict
+			}
+		}
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
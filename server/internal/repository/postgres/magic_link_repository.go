@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type magicLinkRepo struct {
+	db *sql.DB
+}
+
+// NewMagicLinkRepository creates a new magic link token repository.
+func NewMagicLinkRepository(db *sql.DB) *magicLinkRepo {
+	return &magicLinkRepo{
+		db: db,
+	}
+}
+
+// Save stores a new magic link token.
+func (r *magicLinkRepo) Save(ctx context.Context, t *user.MagicLinkToken) (int64, error) {
+	const op = "repository.postgres.magicLink.Save"
+
+	query := `
+		INSERT INTO magic_link_tokens (user_id, token_hash, nonce_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, t.UserID, t.TokenHash, t.NonceHash, t.CreatedAt, t.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetByHash retrieves a magic link token by its hash.
+func (r *magicLinkRepo) GetByHash(ctx context.Context, hash string) (*user.MagicLinkToken, error) {
+	const op = "repository.postgres.magicLink.GetByHash"
+
+	query := `
+		SELECT id, user_id, token_hash, nonce_hash, created_at, expires_at, used_at
+		FROM magic_link_tokens
+		WHERE token_hash = $1
+	`
+
+	var t user.MagicLinkToken
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.TokenHash,
+		&t.NonceHash,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usedAt.Valid {
+		t.UsedAt = &usedAt.Time
+	}
+
+	return &t, nil
+}
+
+// MarkUsed consumes the token unless another request already did.
+func (r *magicLinkRepo) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.magicLink.MarkUsed"
+
+	query := `
+		UPDATE magic_link_tokens
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// InvalidateByUser consumes all outstanding tokens of the user.
+func (r *magicLinkRepo) InvalidateByUser(ctx context.Context, userID int64, at time.Time) error {
+	const op = "repository.postgres.magicLink.InvalidateByUser"
+
+	query := `
+		UPDATE magic_link_tokens
+		SET used_at = $2
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type mfaChallengeRepo struct {
+	db *sql.DB
+}
+
+// NewMFAChallengeRepository creates a new MFA challenge repository.
+func NewMFAChallengeRepository(db *sql.DB) *mfaChallengeRepo {
+	return &mfaChallengeRepo{
+		db: db,
+	}
+}
+
+// Save stores a new challenge.
+func (r *mfaChallengeRepo) Save(ctx context.Context, c *mfa.Challenge) (int64, error) {
+	const op = "repository.postgres.mfaChallenge.Save"
+
+	query := `
+		INSERT INTO mfa_challenges (user_id, token_hash, first_factor, remember_me, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, c.UserID, c.TokenHash, c.FirstFactor, c.RememberMe, c.CreatedAt, c.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetByHash retrieves a challenge by the hash of its token.
+func (r *mfaChallengeRepo) GetByHash(ctx context.Context, hash string) (*mfa.Challenge, error) {
+	const op = "repository.postgres.mfaChallenge.GetByHash"
+
+	query := `
+		SELECT id, user_id, token_hash, first_factor, remember_me, attempts, created_at, expires_at, used_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+	`
+
+	var c mfa.Challenge
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.TokenHash,
+		&c.FirstFactor,
+		&c.RememberMe,
+		&c.Attempts,
+		&c.CreatedAt,
+		&c.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usedAt.Valid {
+		c.UsedAt = &usedAt.Time
+	}
+
+	return &c, nil
+}
+
+// AddAttempt counts a verification attempt against the challenge.
+func (r *mfaChallengeRepo) AddAttempt(ctx context.Context, id int64) (int, error) {
+	const op = "repository.postgres.mfaChallenge.AddAttempt"
+
+	query := `
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, repository.ErrNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempts, nil
+}
+
+// MarkUsed consumes the challenge unless another request already did.
+func (r *mfaChallengeRepo) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.mfaChallenge.MarkUsed"
+
+	query := `
+		UPDATE mfa_challenges
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
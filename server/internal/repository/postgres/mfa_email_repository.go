@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type mfaEmailRepo struct {
+	db *sql.DB
+}
+
+// NewMFAEmailRepository creates a new email OTP enrolment repository.
+func NewMFAEmailRepository(db *sql.DB) *mfaEmailRepo {
+	return &mfaEmailRepo{
+		db: db,
+	}
+}
+
+// Enable turns email OTP on for the user.
+func (r *mfaEmailRepo) Enable(ctx context.Context, userID int64, at time.Time) error {
+	const op = "repository.postgres.mfaEmail.Enable"
+
+	query := `
+		INSERT INTO mfa_email (user_id, enabled_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsEnabled reports whether the user has email OTP turned on.
+func (r *mfaEmailRepo) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	const op = "repository.postgres.mfaEmail.IsEnabled"
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM mfa_email WHERE user_id = $1)
+	`
+
+	var enabled bool
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return enabled, nil
+}
+
+// Disable turns email OTP off for the user.
+func (r *mfaEmailRepo) Disable(ctx context.Context, userID int64) error {
+	const op = "repository.postgres.mfaEmail.Disable"
+
+	query := `
+		DELETE FROM mfa_email
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type mfaSMSRepo struct {
+	db *sql.DB
+}
+
+// NewMFASMSRepository creates a new SMS OTP enrolment repository.
+func NewMFASMSRepository(db *sql.DB) *mfaSMSRepo {
+	return &mfaSMSRepo{
+		db: db,
+	}
+}
+
+// Enable turns SMS OTP on for the user.
+func (r *mfaSMSRepo) Enable(ctx context.Context, userID int64, at time.Time) error {
+	const op = "repository.postgres.mfaSMS.Enable"
+
+	query := `
+		INSERT INTO mfa_sms (user_id, enabled_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsEnabled reports whether the user has SMS OTP turned on.
+func (r *mfaSMSRepo) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	const op = "repository.postgres.mfaSMS.IsEnabled"
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM mfa_sms WHERE user_id = $1)
+	`
+
+	var enabled bool
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return enabled, nil
+}
+
+// Disable turns SMS OTP off for the user.
+func (r *mfaSMSRepo) Disable(ctx context.Context, userID int64) error {
+	const op = "repository.postgres.mfaSMS.Disable"
+
+	query := `
+		DELETE FROM mfa_sms
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type oneTimeCodeRepo struct {
+	db *sql.DB
+}
+
+// NewOneTimeCodeRepository creates a new one-time code repository.
+func NewOneTimeCodeRepository(db *sql.DB) *oneTimeCodeRepo {
+	return &oneTimeCodeRepo{
+		db: db,
+	}
+}
+
+// Save replaces the user's code for the channel and purpose, so only the latest one works.
+func (r *oneTimeCodeRepo) Save(ctx context.Context, c *mfa.OneTimeCode) error {
+	const op = "repository.postgres.oneTimeCode.Save"
+
+	query := `
+		INSERT INTO otp_codes (user_id, channel, purpose, code_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, channel, purpose) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash,
+			attempts = 0,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			used_at = NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, c.UserID, c.Channel, c.Purpose, c.CodeHash, c.CreatedAt, c.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetLatest retrieves the user's code for the channel and purpose.
+func (r *oneTimeCodeRepo) GetLatest(ctx context.Context, userID int64, channel, purpose string) (*mfa.OneTimeCode, error) {
+	const op = "repository.postgres.oneTimeCode.GetLatest"
+
+	query := `
+		SELECT id, user_id, channel, purpose, code_hash, attempts, created_at, expires_at, used_at
+		FROM otp_codes
+		WHERE user_id = $1 AND channel = $2 AND purpose = $3
+	`
+
+	var c mfa.OneTimeCode
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID, channel, purpose).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.Channel,
+		&c.Purpose,
+		&c.CodeHash,
+		&c.Attempts,
+		&c.CreatedAt,
+		&c.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usedAt.Valid {
+		c.UsedAt = &usedAt.Time
+	}
+
+	return &c, nil
+}
+
+// AddAttempt counts a guess against the code.
+func (r *oneTimeCodeRepo) AddAttempt(ctx context.Context, id int64) (int, error) {
+	const op = "repository.postgres.oneTimeCode.AddAttempt"
+
+	query := `
+		UPDATE otp_codes
+		SET attempts = attempts + 1
+		WHERE id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, repository.ErrNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempts, nil
+}
+
+// MarkUsed consumes the code unless another request already did.
+func (r *oneTimeCodeRepo) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.oneTimeCode.MarkUsed"
+
+	query := `
+		UPDATE otp_codes
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
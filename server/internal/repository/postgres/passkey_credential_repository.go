@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+type passkeyCredentialRepo struct {
+	db *sql.DB
+}
+
+// NewPasskeyCredentialRepository creates a new passkey credential repository.
+func NewPasskeyCredentialRepository(db *sql.DB) *passkeyCredentialRepo {
+	return &passkeyCredentialRepo{
+		db: db,
+	}
+}
+
+const selectPasskeyCredentialQuery = `
+	SELECT id, user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count, flags, name, created_at, last_used_at
+	FROM webauthn_credentials
+`
+
+// Save stores a new credential.
+func (r *passkeyCredentialRepo) Save(ctx context.Context, c *passkey.Credential) (int64, error) {
+	const op = "repository.postgres.passkeyCredential.Save"
+
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, public_key, attestation_type, transports, aaguid, sign_count, flags, name, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		c.UserID,
+		c.CredentialID,
+		c.PublicKey,
+		c.AttestationType,
+		pq.Array(c.Transports),
+		c.AAGUID,
+		int64(c.SignCount),
+		int16(c.Flags),
+		c.Name,
+		c.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { // unique_violation
+				return 0, repository.ErrConflict
+			}
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListByUser returns the credentials of a user, oldest first.
+func (r *passkeyCredentialRepo) ListByUser(ctx context.Context, userID int64) ([]passkey.Credential, error) {
+	const op = "repository.postgres.passkeyCredential.ListByUser"
+
+	query := selectPasskeyCredentialQuery + `
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var credentials []passkey.Credential
+	for rows.Next() {
+		c, err := scanPasskeyCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		credentials = append(credentials, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return credentials, nil
+}
+
+// GetByCredentialID retrieves a credential by the ID the authenticator assigned to it.
+func (r *passkeyCredentialRepo) GetByCredentialID(ctx context.Context, credentialID []byte) (*passkey.Credential, error) {
+	const op = "repository.postgres.passkeyCredential.GetByCredentialID"
+
+	query := selectPasskeyCredentialQuery + `
+		WHERE credential_id = $1
+	`
+
+	c, err := scanPasskeyCredential(r.db.QueryRowContext(ctx, query, credentialID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// UpdateUsage records a successful login with the credential.
+func (r *passkeyCredentialRepo) UpdateUsage(ctx context.Context, id int64, signCount uint32, flags uint8, at time.Time) error {
+	const op = "repository.postgres.passkeyCredential.UpdateUsage"
+
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, flags = $3, last_used_at = $4
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, int64(signCount), int16(flags), at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Delete removes a credential of the user.
+func (r *passkeyCredentialRepo) Delete(ctx context.Context, userID, id int64) error {
+	const op = "repository.postgres.passkeyCredential.Delete"
+
+	query := `
+		DELETE FROM webauthn_credentials
+		WHERE id = $1 AND user_id = $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPasskeyCredential(row rowScanner) (*passkey.Credential, error) {
+	var c passkey.Credential
+	var signCount int64
+	var flags int16
+	var lastUsedAt sql.NullTime
+	err := row.Scan(
+		&c.ID,
+		&c.UserID,
+		&c.CredentialID,
+		&c.PublicKey,
+		&c.AttestationType,
+		pq.Array(&c.Transports),
+		&c.AAGUID,
+		&signCount,
+		&flags,
+		&c.Name,
+		&c.CreatedAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SignCount = uint32(signCount)
+	c.Flags = uint8(flags)
+	if lastUsedAt.Valid {
+		c.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type passkeySessionRepo struct {
+	db *sql.DB
+}
+
+// NewPasskeySessionRepository creates a new passkey ceremony session repository.
+func NewPasskeySessionRepository(db *sql.DB) *passkeySessionRepo {
+	return &passkeySessionRepo{
+		db: db,
+	}
+}
+
+// Save stores a ceremony session. Starting a ceremony again with the same token replaces the previous one.
+func (r *passkeySessionRepo) Save(ctx context.Context, s *passkey.Session) error {
+	const op = "repository.postgres.passkeySession.Save"
+
+	query := `
+		INSERT INTO webauthn_sessions (token_hash, ceremony, user_id, data, created_at, expires_at)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6)
+		ON CONFLICT (token_hash) DO UPDATE
+		SET ceremony = EXCLUDED.ceremony,
+			user_id = EXCLUDED.user_id,
+			data = EXCLUDED.data,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query, s.TokenHash, s.Ceremony, s.UserID, s.Data, s.CreatedAt, s.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Take deletes the session so each one can be finished at most once, and returns it.
+func (r *passkeySessionRepo) Take(ctx context.Context, hash, ceremony string) (*passkey.Session, error) {
+	const op = "repository.postgres.passkeySession.Take"
+
+	query := `
+		DELETE FROM webauthn_sessions
+		WHERE token_hash = $1 AND ceremony = $2
+		RETURNING id, token_hash, ceremony, user_id, data, created_at, expires_at
+	`
+
+	var s passkey.Session
+	var userID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, hash, ceremony).Scan(
+		&s.ID,
+		&s.TokenHash,
+		&s.Ceremony,
+		&userID,
+		&s.Data,
+		&s.CreatedAt,
+		&s.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.UserID = userID.Int64
+
+	return &s, nil
+}
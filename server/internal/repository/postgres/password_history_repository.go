@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type passwordHistoryRepo struct {
+	db *sql.DB
+}
+
+// NewPasswordHistoryRepository creates a new password history repository.
+func NewPasswordHistoryRepository(db *sql.DB) *passwordHistoryRepo {
+	return &passwordHistoryRepo{
+		db: db,
+	}
+}
+
+// Add records a password hash and trims the user's history.
+func (r *passwordHistoryRepo) Add(ctx context.Context, userID int64, hash string, keep int, notBefore time.Time) error {
+	const op = "repository.postgres.passwordHistory.Add"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO password_history (user_id, password_hash)
+		VALUES ($1, $2)
+	`
+	if _, err := tx.ExecContext(ctx, insert, userID, hash); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	trim := `
+		DELETE FROM password_history
+		WHERE user_id = $1
+		  AND (
+			created_at < $3
+			OR id NOT IN (
+				SELECT id
+				FROM password_history
+				WHERE user_id = $1
+				ORDER BY created_at DESC, id DESC
+				LIMIT $2
+			)
+		  )
+	`
+	if _, err := tx.ExecContext(ctx, trim, userID, keep, notBefore); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// List returns the user's most recent password hashes, newest first.
+func (r *passwordHistoryRepo) List(ctx context.Context, userID int64, limit int, notBefore time.Time) ([]string, error) {
+	const op = "repository.postgres.passwordHistory.List"
+
+	query := `
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1 AND created_at >= $3
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, notBefore)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type passwordResetRepo struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository creates a new password reset token repository.
+func NewPasswordResetRepository(db *sql.DB) *passwordResetRepo {
+	return &passwordResetRepo{
+		db: db,
+	}
+}
+
+// Save stores a new password reset token.
+func (r *passwordResetRepo) Save(ctx context.Context, t *user.PasswordResetToken) (int64, error) {
+	const op = "repository.postgres.passwordReset.Save"
+
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, t.UserID, t.TokenHash, t.CreatedAt, t.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetByHash retrieves a password reset token by its hash.
+func (r *passwordResetRepo) GetByHash(ctx context.Context, hash string) (*user.PasswordResetToken, error) {
+	const op = "repository.postgres.passwordReset.GetByHash"
+
+	query := `
+		SELECT id, user_id, token_hash, created_at, expires_at, used_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+	`
+
+	var t user.PasswordResetToken
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.TokenHash,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if usedAt.Valid {
+		t.UsedAt = &usedAt.Time
+	}
+
+	return &t, nil
+}
+
+// MarkUsed consumes the token unless another request already did.
+func (r *passwordResetRepo) MarkUsed(ctx context.Context, id int64, at time.Time) error {
+	const op = "repository.postgres.passwordReset.MarkUsed"
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// InvalidateByUser consumes all outstanding tokens of the user.
+func (r *passwordResetRepo) InvalidateByUser(ctx context.Context, userID int64, at time.Time) error {
+	const op = "repository.postgres.passwordReset.InvalidateByUser"
+
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = $2
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type recoveryCodeRepo struct {
+	db *sql.DB
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository.
+func NewRecoveryCodeRepository(db *sql.DB) *recoveryCodeRepo {
+	return &recoveryCodeRepo{
+		db: db,
+	}
+}
+
+// Replace swaps all codes of the user for the given ones in a single transaction.
+func (r *recoveryCodeRepo) Replace(ctx context.Context, userID int64, hashes []string, at time.Time) error {
+	const op = "repository.postgres.recoveryCode.Replace"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	insert := `
+		INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+		VALUES ($1, $2, $3)
+	`
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, insert, userID, hash, at); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Use consumes one unused code matching hash.
+func (r *recoveryCodeRepo) Use(ctx context.Context, userID int64, hash string, at time.Time) error {
+	const op = "repository.postgres.recoveryCode.Use"
+
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = $3
+		WHERE id = (
+			SELECT id
+			FROM mfa_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+			FOR UPDATE
+		)
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, hash, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// CountUnused returns the number of codes the user has left.
+func (r *recoveryCodeRepo) CountUnused(ctx context.Context, userID int64) (int, error) {
+	const op = "repository.postgres.recoveryCode.CountUnused"
+
+	query := `
+		SELECT COUNT(*)
+		FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	var n int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// DeleteByUser removes all codes of the user.
+func (r *recoveryCodeRepo) DeleteByUser(ctx context.Context, userID int64) error {
+	const op = "repository.postgres.recoveryCode.DeleteByUser"
+
+	query := `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+type refreshTokenRepo struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository.
+func NewRefreshTokenRepository(db *sql.DB) *refreshTokenRepo {
+	return &refreshTokenRepo{
+		db: db,
+	}
+}
+
+const insertRefreshTokenQuery = `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, created_at, expires_at, family_expires_at, auth_time, amr)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id
+`
+
+// Save saves a new refresh token to the database.
+func (r *refreshTokenRepo) Save(ctx context.Context, t *token.RefreshToken) (int64, error) {
+	const op = "repository.postgres.refreshToken.Save"
+
+	id, err := insertRefreshToken(ctx, r.db, t)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// Rotate marks the old token as rotated and saves its successor atomically.
+func (r *refreshTokenRepo) Rotate(ctx context.Context, oldID int64, next *token.RefreshToken) (int64, error) {
+	const op = "repository.postgres.refreshToken.Rotate"
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE refresh_tokens
+		SET rotated_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	res, err := tx.ExecContext(ctx, query, oldID, next.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return 0, repository.ErrConflict
+	}
+
+	id, err := insertRefreshToken(ctx, tx, next)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+const selectRefreshTokenQuery = `
+	SELECT id, user_id, family_id, token_hash, created_at, expires_at, family_expires_at, auth_time, amr, rotated_at, revoked_at
+	FROM refresh_tokens
+`
+
+// GetByHash retrieves a refresh token by its hash from the database.
+func (r *refreshTokenRepo) GetByHash(ctx context.Context, hash string) (*token.RefreshToken, error) {
+	const op = "repository.postgres.refreshToken.GetByHash"
+
+	query := selectRefreshTokenQuery + `
+		WHERE token_hash = $1
+	`
+
+	t, err := scanRefreshToken(r.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// GetActiveByFamily retrieves the current token of a family.
+func (r *refreshTokenRepo) GetActiveByFamily(ctx context.Context, familyID string) (*token.RefreshToken, error) {
+	const op = "repository.postgres.refreshToken.GetActiveByFamily"
+
+	query := selectRefreshTokenQuery + `
+		WHERE family_id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	t, err := scanRefreshToken(r.db.QueryRowContext(ctx, query, familyID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// RevokeFamily revokes every token of the given family.
+func (r *refreshTokenRepo) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	const op = "repository.postgres.refreshToken.RevokeFamily"
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, familyID, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertRefreshToken(ctx context.Context, q queryRower, t *token.RefreshToken) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(
+		ctx,
+		insertRefreshTokenQuery,
+		t.UserID,
+		t.FamilyID,
+		t.TokenHash,
+		t.CreatedAt,
+		t.ExpiresAt,
+		t.FamilyExpiresAt,
+		t.Auth.Time,
+		pq.Array(t.Auth.Methods),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// RevokeByUser revokes all active tokens of the user, except those of keepFamilyID,
+// and returns the affected families.
+func (r *refreshTokenRepo) RevokeByUser(ctx context.Context, userID int64, keepFamilyID string, at time.Time) ([]string, error) {
+	const op = "repository.postgres.refreshToken.RevokeByUser"
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL AND family_expires_at > $2 AND family_id <> $3
+		RETURNING family_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, at, keepFamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]struct{})
+	var families []string
+	for rows.Next() {
+		var familyID string
+		if err := rows.Scan(&familyID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, ok := seen[familyID]; ok {
+			continue
+		}
+		seen[familyID] = struct{}{}
+		families = append(families, familyID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return families, nil
+}
+
+func scanRefreshToken(row *sql.Row) (*token.RefreshToken, error) {
+	var t token.RefreshToken
+	var rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.FamilyID,
+		&t.TokenHash,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&t.FamilyExpiresAt,
+		&t.Auth.Time,
+		pq.Array(&t.Auth.Methods),
+		&rotatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotatedAt.Valid {
+		t.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/session"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+type sessionRepo struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new session repository.
+func NewSessionRepository(db *sql.DB) *sessionRepo {
+	return &sessionRepo{
+		db: db,
+	}
+}
+
+// Save saves a new session to the database.
+func (r *sessionRepo) Save(ctx context.Context, s *session.Session) error {
+	const op = "repository.postgres.session.Save"
+
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, browser, os, device, ip, created_at, last_seen_at, expires_at, remember_me, idle_timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		s.ID,
+		s.UserID,
+		s.UserAgent,
+		s.Browser,
+		s.OS,
+		s.Device,
+		s.IP,
+		s.CreatedAt,
+		s.LastSeenAt,
+		s.ExpiresAt,
+		s.RememberMe,
+		int64(s.IdleTimeout/time.Second),
+	)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { // unique_violation
+				return repository.ErrConflict
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+const selectSessionQuery = `
+	SELECT id, user_id, user_agent, browser, os, device, ip, created_at, last_seen_at, expires_at, revoked_at, remember_me, idle_timeout_seconds
+	FROM sessions
+`
+
+// Get retrieves a session of the user by its ID.
+func (r *sessionRepo) Get(ctx context.Context, userID int64, id string) (*session.Session, error) {
+	const op = "repository.postgres.session.Get"
+
+	query := selectSessionQuery + `
+		WHERE id = $1 AND user_id = $2
+	`
+
+	s, err := scanSession(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// ListActive retrieves the unrevoked, unexpired and not idle sessions of the user.
+func (r *sessionRepo) ListActive(ctx context.Context, userID int64, now time.Time) ([]session.Session, error) {
+	const op = "repository.postgres.session.ListActive"
+
+	query := selectSessionQuery + `
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+			AND (idle_timeout_seconds = 0 OR last_seen_at + idle_timeout_seconds * INTERVAL '1 second' > $2)
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []session.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sessions = append(sessions, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// Touch updates when and from where the session was last seen. Revoked
+// sessions and sessions that were already idle at the given time are left
+// alone, so late activity can't revive them.
+func (r *sessionRepo) Touch(ctx context.Context, id, ip string, at time.Time) error {
+	const op = "repository.postgres.session.Touch"
+
+	query := `
+		UPDATE sessions
+		SET last_seen_at = GREATEST(last_seen_at, $3),
+			ip = COALESCE(NULLIF($2, ''), ip)
+		WHERE id = $1 AND revoked_at IS NULL
+			AND (idle_timeout_seconds = 0 OR last_seen_at + idle_timeout_seconds * INTERVAL '1 second' > $3)
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, ip, at); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Revoke marks the given sessions as revoked.
+func (r *sessionRepo) Revoke(ctx context.Context, at time.Time, ids ...string) error {
+	const op = "repository.postgres.session.Revoke"
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE sessions
+		SET revoked_at = $1
+		WHERE id = ANY($2) AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, pq.Array(ids)); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func scanSession(row rowScanner) (*session.Session, error) {
+	var s session.Session
+	var revokedAt sql.NullTime
+	var idleTimeout int64
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.UserAgent,
+		&s.Browser,
+		&s.OS,
+		&s.Device,
+		&s.IP,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.ExpiresAt,
+		&revokedAt,
+		&s.RememberMe,
+		&idleTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	s.IdleTimeout = time.Duration(idleTimeout) * time.Second
+
+	return &s, nil
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+type totpRepo struct {
+	db *sql.DB
+}
+
+// NewTOTPRepository creates a new TOTP enrolment repository.
+func NewTOTPRepository(db *sql.DB) *totpRepo {
+	return &totpRepo{
+		db: db,
+	}
+}
+
+// Save stores an unconfirmed enrolment unless the user already has a confirmed one.
+func (r *totpRepo) Save(ctx context.Context, t *mfa.TOTP) error {
+	const op = "repository.postgres.totp.Save"
+
+	query := `
+		INSERT INTO mfa_totp (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at, last_used_step = 0
+		WHERE mfa_totp.confirmed_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, t.UserID, t.Secret, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// GetByUser retrieves the enrolment of a user.
+func (r *totpRepo) GetByUser(ctx context.Context, userID int64) (*mfa.TOTP, error) {
+	const op = "repository.postgres.totp.GetByUser"
+
+	query := `
+		SELECT user_id, secret, created_at, confirmed_at, last_used_step, attempts, locked_until
+		FROM mfa_totp
+		WHERE user_id = $1
+	`
+
+	var t mfa.TOTP
+	var confirmedAt, lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.CreatedAt,
+		&confirmedAt,
+		&t.LastUsedStep,
+		&t.Attempts,
+		&lockedUntil,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if confirmedAt.Valid {
+		t.ConfirmedAt = &confirmedAt.Time
+	}
+	if lockedUntil.Valid {
+		t.LockedUntil = &lockedUntil.Time
+	}
+
+	return &t, nil
+}
+
+// Confirm enables the enrolment.
+func (r *totpRepo) Confirm(ctx context.Context, userID int64, at time.Time) error {
+	const op = "repository.postgres.totp.Confirm"
+
+	query := `
+		UPDATE mfa_totp
+		SET confirmed_at = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// UseStep advances the last accepted time step and clears the attempt counter.
+func (r *totpRepo) UseStep(ctx context.Context, userID int64, step int64) error {
+	const op = "repository.postgres.totp.UseStep"
+
+	query := `
+		UPDATE mfa_totp
+		SET last_used_step = $2, attempts = 0
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return nil
+}
+
+// AddAttempt counts a code tried against the enrolment.
+func (r *totpRepo) AddAttempt(ctx context.Context, userID int64) (int, error) {
+	const op = "repository.postgres.totp.AddAttempt"
+
+	query := `
+		UPDATE mfa_totp
+		SET attempts = attempts + 1
+		WHERE user_id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&attempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, repository.ErrNotFound
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return attempts, nil
+}
+
+// Lock refuses codes until the given time.
+func (r *totpRepo) Lock(ctx context.Context, userID int64, until time.Time) error {
+	const op = "repository.postgres.totp.Lock"
+
+	query := `
+		UPDATE mfa_totp
+		SET attempts = 0, locked_until = $2
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, until); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Delete removes the enrolment of a user.
+func (r *totpRepo) Delete(ctx context.Context, userID int64) error {
+	const op = "repository.postgres.totp.Delete"
+
+	query := `
+		DELETE FROM mfa_totp
+		WHERE user_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
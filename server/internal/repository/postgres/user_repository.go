@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/LullNil/authx-go/domain/user"
 	"github.com/LullNil/authx-go/internal/repository"
@@ -58,18 +59,12 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*user.User, er
 	const op = "repository.postgres.user.GetByEmail"
 
 	query := `
-		SELECT id, email, username, password
+		SELECT id, email, username, password, email_verified_at, phone, phone_verified_at, pending_phone
 		FROM users
 		WHERE email = $1
 	`
 
-	var u user.User
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Username,
-		&u.Password,
-	)
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, repository.ErrNotFound
@@ -77,7 +72,7 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (*user.User, er
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &u, nil
+	return u, nil
 }
 
 // GetByID retrieves an user by ID from the database.
@@ -85,17 +80,12 @@ func (r *userRepo) GetByID(ctx context.Context, id int64) (*user.User, error) {
 	const op = "repository.postgres.user.GetByID"
 
 	query := `
-		SELECT id, email, username
+		SELECT id, email, username, password, email_verified_at, phone, phone_verified_at, pending_phone
 		FROM users
 		WHERE id = $1
 	`
 
-	var u user.User
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Username,
-	)
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, repository.ErrNotFound
@@ -103,7 +93,7 @@ func (r *userRepo) GetByID(ctx context.Context, id int64) (*user.User, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &u, nil
+	return u, nil
 }
 
 // GetByUsername retrieves user by username
@@ -111,18 +101,12 @@ func (r *userRepo) GetByUsername(ctx context.Context, username string) (*user.Us
 	const op = "repository.postgres.user.GetByUsername"
 
 	query := `
-		SELECT id, email, username, password
+		SELECT id, email, username, password, email_verified_at, phone, phone_verified_at, pending_phone
 		FROM users
 		WHERE username = $1
 	`
 
-	var u user.User
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Username,
-		&u.Password,
-	)
+	u, err := scanUser(r.db.QueryRowContext(ctx, query, username))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, repository.ErrNotFound
@@ -130,5 +114,137 @@ func (r *userRepo) GetByUsername(ctx context.Context, username string) (*user.Us
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	return u, nil
+}
+
+// MarkEmailVerified marks the email of a user as verified unless it has changed since.
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id int64, email string, at time.Time) error {
+	const op = "repository.postgres.user.MarkEmailVerified"
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, $3)
+		WHERE id = $1 AND email = $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, email, at)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// SetPendingPhone stores a number waiting to be verified.
+func (r *userRepo) SetPendingPhone(ctx context.Context, id int64, phone string) error {
+	const op = "repository.postgres.user.SetPendingPhone"
+
+	query := `
+		UPDATE users
+		SET pending_phone = $2
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, phone)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// MarkPhoneVerified replaces the phone of a user with the pending number
+// unless another one has been set since.
+func (r *userRepo) MarkPhoneVerified(ctx context.Context, id int64, phone string, at time.Time) error {
+	const op = "repository.postgres.user.MarkPhoneVerified"
+
+	query := `
+		UPDATE users
+		SET phone = pending_phone, phone_verified_at = $3, pending_phone = NULL
+		WHERE id = $1 AND pending_phone = $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, phone, at)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			if pqErr.Code == "23505" { // unique_violation
+				return repository.ErrConflict
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (r *userRepo) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	const op = "repository.postgres.user.UpdatePassword"
+
+	query := `
+		UPDATE users
+		SET password = $2
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id, hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row *sql.Row) (*user.User, error) {
+	var u user.User
+	var emailVerifiedAt, phoneVerifiedAt sql.NullTime
+	var phone, pendingPhone sql.NullString
+	if err := row.Scan(&u.ID, &u.Email, &u.Username, &u.Password, &emailVerifiedAt, &phone, &phoneVerifiedAt, &pendingPhone); err != nil {
+		return nil, err
+	}
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if phone.Valid {
+		u.Phone = &phone.String
+	}
+	if phoneVerifiedAt.Valid {
+		u.PhoneVerifiedAt = &phoneVerifiedAt.Time
+	}
+	if pendingPhone.Valid {
+		u.PendingPhone = &pendingPhone.String
+	}
 	return &u, nil
 }
@@ -0,0 +1,139 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// SetupEmailOTP emails a code to the user's address. Entering it proves the
+// address receives mail and turns email OTP on.
+func (s *service) SetupEmailOTP(ctx context.Context, userID int64, locale string) error {
+	const op = "service.mfa.SetupEmailOTP"
+
+	enabled, err := s.emailOTP.IsEnabled(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if enabled {
+		return apperr.New(http.StatusConflict, "email otp is already enabled")
+	}
+
+	if err := s.sendEmailCode(ctx, userID, mfa.PurposeSetup, locale); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailOTP enables email OTP with the code sent by SetupEmailOTP.
+func (s *service) ConfirmEmailOTP(ctx context.Context, userID int64, code string) error {
+	const op = "service.mfa.ConfirmEmailOTP"
+
+	if err := s.verifyEmailCode(ctx, userID, mfa.PurposeSetup, code); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.emailOTP.Enable(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DisableEmailOTP turns email OTP off. Recovery codes are removed with the last second factor.
+func (s *service) DisableEmailOTP(ctx context.Context, userID int64, stepUp *mfa.StepUp) error {
+	const op = "service.mfa.DisableEmailOTP"
+
+	if err := s.RequireStepUp(ctx, userID, stepUp); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.emailOTP.Disable(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := s.secondFactors(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !f.any() {
+		if err := s.recoveryCodes.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// SendLoginCode emails a code for the second step of a login.
+func (s *service) SendLoginCode(ctx context.Context, req mfa.ChallengeTokenRequest) error {
+	const op = "service.mfa.SendLoginCode"
+
+	c, err := s.pendingChallenge(ctx, req.Token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !mfa.Independent(c.FirstFactor, mfa.MethodEmailOTP) {
+		return errDependentMethod
+	}
+
+	enabled, err := s.emailOTP.IsEnabled(ctx, c.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !enabled {
+		return apperr.New(http.StatusBadRequest, "email otp is not enabled")
+	}
+
+	if err := s.sendEmailCode(ctx, c.UserID, mfa.PurposeLogin, req.Locale); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sendEmailCode replaces the user's email code for the purpose with a new one and mails it.
+func (s *service) sendEmailCode(ctx context.Context, userID int64, purpose, locale string) error {
+	if !s.codeLimiter.Allow(strconv.FormatInt(userID, 10) + ":" + purpose) {
+		return apperr.New(http.StatusTooManyRequests, "please wait before requesting another code")
+	}
+
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	cfg := s.cfg.EmailOTP
+	code, err := s.newCode(ctx, u.ID, mfa.ChannelEmail, u.Email, purpose, cfg.TTL)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.mailTemplates.Render("email_otp", locale, map[string]any{
+		"Username":         u.Username,
+		"Code":             code,
+		"ExpiresInMinutes": int(cfg.TTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = u.Email
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// verifyEmailCode consumes the user's email code for the purpose if code matches it.
+func (s *service) verifyEmailCode(ctx context.Context, userID int64, purpose, code string) error {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.verifyCode(ctx, u.ID, mfa.ChannelEmail, u.Email, purpose, code, s.cfg.EmailOTP.MaxAttempts)
+}
@@ -0,0 +1,560 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/mailer"
+	"github.com/LullNil/authx-go/internal/lib/ratelimit"
+	"github.com/LullNil/authx-go/internal/lib/secretbox"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/lib/sms"
+	"github.com/LullNil/authx-go/internal/lib/totp"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrCodeSize is the width and height of enrolment QR codes in pixels.
+const qrCodeSize = 256
+
+var (
+	errInvalidCode      = apperr.New(http.StatusUnauthorized, "invalid code")
+	errInvalidChallenge = apperr.New(http.StatusUnauthorized, "invalid or expired mfa token")
+	errTOTPLocked       = apperr.New(http.StatusTooManyRequests, "too many wrong codes, try again later")
+	errDependentMethod  = apperr.New(http.StatusBadRequest, "this method can't complete this login")
+)
+
+// UserStore loads the account codes are sent to and keeps its phone number.
+type UserStore interface {
+	GetByID(ctx context.Context, id int64) (*user.User, error)
+	SetPendingPhone(ctx context.Context, id int64, phone string) error
+	MarkPhoneVerified(ctx context.Context, id int64, phone string, at time.Time) error
+}
+
+type service struct {
+	totpRepo      mfa.TOTPRepository
+	recoveryCodes mfa.RecoveryCodeRepository
+	challenges    mfa.ChallengeRepository
+	emailOTP      mfa.EmailOTPRepository
+	smsOTP        mfa.SMSOTPRepository
+	codes         mfa.OneTimeCodeRepository
+	passkeys      passkey.Service
+	users         UserStore
+	secrets       *secretbox.Box
+	mailer        mailer.Mailer
+	mailTemplates *mailer.Templates
+	sms           sms.Sender
+	codeLimiter   *ratelimit.Limiter
+	phoneLimiter  *ratelimit.Limiter
+	ipLimiter     *ratelimit.Limiter
+	cfg           config.MFA
+	logger        *slog.Logger
+}
+
+// NewService returns a new MFA service.
+func NewService(
+	totpRepo mfa.TOTPRepository,
+	recoveryCodes mfa.RecoveryCodeRepository,
+	challenges mfa.ChallengeRepository,
+	emailOTP mfa.EmailOTPRepository,
+	smsOTP mfa.SMSOTPRepository,
+	codes mfa.OneTimeCodeRepository,
+	passkeys passkey.Service,
+	users UserStore,
+	secrets *secretbox.Box,
+	mailer mailer.Mailer,
+	mailTemplates *mailer.Templates,
+	sms sms.Sender,
+	cfg config.MFA,
+	cacheSize int,
+	logger *slog.Logger,
+) mfa.Service {
+	return &service{
+		totpRepo:      totpRepo,
+		recoveryCodes: recoveryCodes,
+		challenges:    challenges,
+		emailOTP:      emailOTP,
+		smsOTP:        smsOTP,
+		codes:         codes,
+		passkeys:      passkeys,
+		users:         users,
+		secrets:       secrets,
+		mailer:        mailer,
+		mailTemplates: mailTemplates,
+		sms:           sms,
+		codeLimiter:   ratelimit.New(1, cfg.EmailOTP.ResendInterval, cacheSize),
+		phoneLimiter:  ratelimit.New(cfg.SMSOTP.PerNumber.Requests, cfg.SMSOTP.PerNumber.Window, cacheSize),
+		ipLimiter:     ratelimit.New(cfg.SMSOTP.PerIP.Requests, cfg.SMSOTP.PerIP.Window, cacheSize),
+		cfg:           cfg,
+		logger:        logger,
+	}
+}
+
+// SetupTOTP generates a new secret for the user. Setting up again before
+// confirming replaces the previous secret.
+func (s *service) SetupTOTP(ctx context.Context, userID int64, account string) (*mfa.TOTPSetup, error) {
+	const op = "service.mfa.SetupTOTP"
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealed, err := s.secrets.Seal(secret, userAD(userID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.totpRepo.Save(ctx, &mfa.TOTP{
+		UserID:    userID,
+		Secret:    sealed,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, apperr.New(http.StatusConflict, "totp is already enabled")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	uri := totp.URI(s.cfg.Issuer, account, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &mfa.TOTPSetup{
+		Secret: totp.EncodeSecret(secret),
+		URI:    uri,
+		QRCode: png,
+	}, nil
+}
+
+// ConfirmTOTP enables a pending enrolment once the user proves the app produces valid codes.
+func (s *service) ConfirmTOTP(ctx context.Context, userID int64, code string) (*mfa.RecoveryCodes, error) {
+	const op = "service.mfa.ConfirmTOTP"
+
+	t, err := s.totpRepo.GetByUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(http.StatusBadRequest, "totp setup was not started")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if t.ConfirmedAt != nil {
+		return nil, apperr.New(http.StatusConflict, "totp is already enabled")
+	}
+
+	if err := s.verifyTOTP(ctx, t, code); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// generate the codes first, so a failure can't leave TOTP enabled without them
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.totpRepo.Confirm(ctx, userID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, apperr.New(http.StatusConflict, "totp is already enabled")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP removes the enrolment, and the recovery codes unless another second factor is left.
+func (s *service) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	const op = "service.mfa.DisableTOTP"
+
+	if err := s.Verify(ctx, userID, mfa.MethodTOTP, code); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.totpRepo.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// recovery codes stay while the user has another second factor
+	f, err := s.secondFactors(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !f.any() {
+		if err := s.recoveryCodes.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes with new ones.
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID int64) (*mfa.RecoveryCodes, error) {
+	const op = "service.mfa.RegenerateRecoveryCodes"
+
+	f, err := s.secondFactors(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !f.any() {
+		return nil, apperr.New(http.StatusBadRequest, "mfa is not enabled")
+	}
+
+	codes, err := s.newRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return codes, nil
+}
+
+// RecoveryCodesRemaining returns the number of unused recovery codes.
+func (s *service) RecoveryCodesRemaining(ctx context.Context, userID int64) (int, error) {
+	const op = "service.mfa.RecoveryCodesRemaining"
+
+	n, err := s.recoveryCodes.CountUnused(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// Methods returns the enabled second factors of the user. Recovery codes
+// only count while the user has another second factor.
+func (s *service) Methods(ctx context.Context, userID int64) ([]string, error) {
+	const op = "service.mfa.Methods"
+
+	f, err := s.secondFactors(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var methods []string
+	if f.totp {
+		methods = append(methods, mfa.MethodTOTP)
+	}
+	if f.passkeys {
+		methods = append(methods, mfa.MethodWebAuthn)
+	}
+	if f.email {
+		methods = append(methods, mfa.MethodEmailOTP)
+	}
+	if f.sms {
+		methods = append(methods, mfa.MethodSMSOTP)
+	}
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	remaining, err := s.recoveryCodes.CountUnused(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if remaining > 0 {
+		methods = append(methods, mfa.MethodRecoveryCode)
+	}
+
+	return methods, nil
+}
+
+// Verify checks a second factor code of the user.
+func (s *service) Verify(ctx context.Context, userID int64, method, code string) error {
+	const op = "service.mfa.Verify"
+
+	switch method {
+	case mfa.MethodTOTP:
+		t, err := s.totpRepo.GetByUser(ctx, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return errInvalidCode
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if t.ConfirmedAt == nil {
+			return errInvalidCode
+		}
+		return s.verifyTOTP(ctx, t, code)
+
+	case mfa.MethodRecoveryCode:
+		err := s.recoveryCodes.Use(ctx, userID, s.hashRecoveryCode(userID, code), time.Now())
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return errInvalidCode
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+
+	case mfa.MethodEmailOTP:
+		enabled, err := s.emailOTP.IsEnabled(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if !enabled {
+			return errInvalidCode
+		}
+		return s.verifyEmailCode(ctx, userID, mfa.PurposeLogin, code)
+
+	case mfa.MethodSMSOTP:
+		return s.verifySMSCode(ctx, userID, mfa.PurposeLogin, code)
+
+	default:
+		return apperr.New(http.StatusBadRequest, "unsupported mfa method")
+	}
+}
+
+// StartChallenge creates a single-use challenge for the second step of a login.
+func (s *service) StartChallenge(ctx context.Context, userID int64, firstFactor string, rememberMe bool, methods []string) (*mfa.ChallengeResponse, error) {
+	const op = "service.mfa.StartChallenge"
+
+	raw, err := securetoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.cfg.ChallengeTTL)
+	_, err = s.challenges.Save(ctx, &mfa.Challenge{
+		UserID:      userID,
+		TokenHash:   securetoken.Hash(raw),
+		FirstFactor: firstFactor,
+		RememberMe:  rememberMe,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &mfa.ChallengeResponse{
+		MFARequired: true,
+		Token:       raw,
+		Methods:     methods,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// BeginWebAuthn starts a passkey ceremony bound to the challenge token.
+func (s *service) BeginWebAuthn(ctx context.Context, req mfa.ChallengeTokenRequest) (*passkey.Options, error) {
+	const op = "service.mfa.BeginWebAuthn"
+
+	c, err := s.pendingChallenge(ctx, req.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	opts, err := s.passkeys.BeginAssertion(ctx, c.UserID, req.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return opts, nil
+}
+
+// CompleteChallenge verifies the second factor of a login.
+func (s *service) CompleteChallenge(ctx context.Context, req mfa.CompleteChallengeRequest) (*mfa.Challenge, error) {
+	const op = "service.mfa.CompleteChallenge"
+
+	c, err := s.pendingChallenge(ctx, req.Token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !mfa.Independent(c.FirstFactor, req.Method) {
+		return nil, errDependentMethod
+	}
+
+	// count the attempt before checking the code, so concurrent guesses are limited too
+	attempts, err := s.challenges.AddAttempt(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if attempts > s.cfg.MaxAttempts {
+		return nil, apperr.New(http.StatusTooManyRequests, "too many attempts, log in again")
+	}
+
+	if req.Method == mfa.MethodWebAuthn {
+		err = s.passkeys.FinishAssertion(ctx, c.UserID, req.Token, req.Credential)
+	} else {
+		err = s.Verify(ctx, c.UserID, req.Method, req.Code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.challenges.MarkUsed(ctx, c.ID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errInvalidChallenge
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// verifyTOTP checks a code and records its time step so it can't be replayed.
+// After MaxAttempts wrong codes in a row the enrolment is locked for
+// TOTPLockout, whichever flow the codes were tried from.
+func (s *service) verifyTOTP(ctx context.Context, t *mfa.TOTP, code string) error {
+	now := time.Now()
+	if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+		return errTOTPLocked
+	}
+
+	// count the attempt before checking the code, so concurrent guesses are limited too
+	attempts, err := s.totpRepo.AddAttempt(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidCode
+		}
+		return err
+	}
+	if attempts > s.cfg.MaxAttempts {
+		if err := s.totpRepo.Lock(ctx, t.UserID, now.Add(s.cfg.TOTPLockout)); err != nil {
+			return err
+		}
+		return errTOTPLocked
+	}
+
+	secret, err := s.secrets.Open(t.Secret, userAD(t.UserID))
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, now, s.cfg.TOTPSkew)
+	if !ok {
+		return errInvalidCode
+	}
+
+	if err := s.totpRepo.UseStep(ctx, t.UserID, step); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return errInvalidCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+// pendingChallenge returns the challenge of token if it can still be completed.
+func (s *service) pendingChallenge(ctx context.Context, token string) (*mfa.Challenge, error) {
+	c, err := s.challenges.GetByHash(ctx, securetoken.Hash(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidChallenge
+		}
+		return nil, err
+	}
+
+	if c.UsedAt != nil || !time.Now().Before(c.ExpiresAt) {
+		return nil, errInvalidChallenge
+	}
+
+	return c, nil
+}
+
+// factors are the second factors a user has enabled.
+type factors struct {
+	totp     bool
+	passkeys bool
+	email    bool
+	sms      bool
+}
+
+func (f factors) any() bool {
+	return f.totp || f.passkeys || f.email || f.sms
+}
+
+func (s *service) secondFactors(ctx context.Context, userID int64) (factors, error) {
+	var f factors
+
+	t, err := s.totpRepo.GetByUser(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return f, err
+	}
+	f.totp = t != nil && t.ConfirmedAt != nil
+
+	credentials, err := s.passkeys.ListCredentials(ctx, userID)
+	if err != nil {
+		return f, err
+	}
+	f.passkeys = len(credentials) > 0
+
+	f.email, err = s.emailOTP.IsEnabled(ctx, userID)
+	if err != nil {
+		return f, err
+	}
+
+	_, f.sms, err = s.smsFactor(ctx, userID)
+	if err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns them in plain text.
+// They are shown once; only hashes are stored.
+func (s *service) newRecoveryCodes(ctx context.Context, userID int64) (*mfa.RecoveryCodes, error) {
+	codes := make([]string, s.cfg.RecoveryCodes)
+	hashes := make([]string, s.cfg.RecoveryCodes)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = s.hashRecoveryCode(userID, code)
+	}
+
+	if err := s.recoveryCodes.Replace(ctx, userID, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &mfa.RecoveryCodes{Codes: codes}, nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCode returns a code like "abcde-fghij" with 50 bits of entropy.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// hashRecoveryCode hashes a code ignoring case, spaces and dashes. Codes
+// only have 50 bits of entropy, so the hash is keyed with the server secret
+// to resist offline guessing, and bound to the user so equal codes of
+// different users don't share a hash.
+func (s *service) hashRecoveryCode(userID int64, code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hex.EncodeToString(s.secrets.MAC(append(userAD(userID), ":recovery:"+code...)))
+}
+
+// userAD binds an encrypted secret to its owner, so it can't be copied to another user.
+func userAD(userID int64) []byte {
+	return []byte("user:" + strconv.FormatInt(userID, 10))
+}
@@ -0,0 +1,198 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/internal/lib/secretbox"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/repository"
+)
+
+const testUserID = 42
+
+func TestMagicLinkLoginCannotUseEmailOTP(t *testing.T) {
+	ctx := context.Background()
+	svc, challenges := newTestService(t)
+
+	methods := mfa.IndependentMethods("email", []string{mfa.MethodEmailOTP, mfa.MethodRecoveryCode})
+	if !slices.Equal(methods, []string{mfa.MethodRecoveryCode}) {
+		t.Fatalf("methods after a magic link = %v, want only recovery codes", methods)
+	}
+
+	codes, err := svc.(*service).newRecoveryCodes(ctx, testUserID)
+	if err != nil {
+		t.Fatalf("newRecoveryCodes: %v", err)
+	}
+	challenge, err := svc.StartChallenge(ctx, testUserID, "email", false, methods)
+	if err != nil {
+		t.Fatalf("StartChallenge: %v", err)
+	}
+
+	if err := svc.SendLoginCode(ctx, mfa.ChallengeTokenRequest{Token: challenge.Token}); !errors.Is(err, errDependentMethod) {
+		t.Errorf("SendLoginCode = %v, want %v", err, errDependentMethod)
+	}
+	_, err = svc.CompleteChallenge(ctx, mfa.CompleteChallengeRequest{
+		Token:  challenge.Token,
+		Method: mfa.MethodEmailOTP,
+		Code:   "123456",
+	})
+	if !errors.Is(err, errDependentMethod) {
+		t.Errorf("CompleteChallenge with email_otp = %v, want %v", err, errDependentMethod)
+	}
+
+	c, err := svc.CompleteChallenge(ctx, mfa.CompleteChallengeRequest{
+		Token:  challenge.Token,
+		Method: mfa.MethodRecoveryCode,
+		Code:   codes.Codes[0],
+	})
+	if err != nil {
+		t.Fatalf("CompleteChallenge with a recovery code: %v", err)
+	}
+	if c.UserID != testUserID || c.FirstFactor != "email" {
+		t.Errorf("unexpected challenge %+v", c)
+	}
+	if got := challenges.byID[c.ID].Attempts; got != 1 {
+		t.Errorf("challenge has %d attempts, want 1: the email_otp attempt must not count", got)
+	}
+}
+
+func TestPasswordLoginKeepsEmailOTP(t *testing.T) {
+	methods := []string{mfa.MethodEmailOTP, mfa.MethodRecoveryCode}
+	if got := mfa.IndependentMethods("pwd", methods); !slices.Equal(got, methods) {
+		t.Errorf("methods after a password = %v, want %v", got, methods)
+	}
+}
+
+func TestRecoveryCodeHashes(t *testing.T) {
+	svc, _ := newTestService(t)
+	s := svc.(*service)
+
+	if s.hashRecoveryCode(1, "abcde-fghij") == s.hashRecoveryCode(2, "abcde-fghij") {
+		t.Error("the same code hashes the same for different users")
+	}
+	if s.hashRecoveryCode(1, "abcde-fghij") != s.hashRecoveryCode(1, "ABCDE FGHIJ") {
+		t.Error("case, spaces and dashes change the hash")
+	}
+	if s.hashRecoveryCode(1, "abcde-fghij") == securetoken.Hash("abcdefghij") {
+		t.Error("recovery codes are hashed without a key")
+	}
+}
+
+func newTestService(t *testing.T) (mfa.Service, *memChallenges) {
+	t.Helper()
+
+	box, err := secretbox.New(make([]byte, secretbox.KeySize))
+	if err != nil {
+		t.Fatalf("secretbox.New: %v", err)
+	}
+
+	cfg := config.MFA{
+		RecoveryCodes: 4,
+		ChallengeTTL:  time.Minute,
+		MaxAttempts:   5,
+	}
+	challenges := &memChallenges{byID: map[int64]*mfa.Challenge{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	svc := NewService(nil, &memRecoveryCodes{}, challenges, nil, nil, nil, nil, nil, box, nil, nil, nil, cfg, 100, logger)
+	return svc, challenges
+}
+
+type memChallenges struct {
+	mu   sync.Mutex
+	byID map[int64]*mfa.Challenge
+}
+
+func (r *memChallenges) Save(_ context.Context, c *mfa.Challenge) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c.ID = int64(len(r.byID) + 1)
+	stored := *c
+	r.byID[c.ID] = &stored
+	return c.ID, nil
+}
+
+func (r *memChallenges) GetByHash(_ context.Context, hash string) (*mfa.Challenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.byID {
+		if c.TokenHash == hash {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memChallenges) AddAttempt(_ context.Context, id int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byID[id].Attempts++
+	return r.byID[id].Attempts, nil
+}
+
+func (r *memChallenges) MarkUsed(_ context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byID[id].UsedAt != nil {
+		return repository.ErrConflict
+	}
+	r.byID[id].UsedAt = &at
+	return nil
+}
+
+type memRecoveryCodes struct {
+	mu     sync.Mutex
+	unused map[int64][]string
+}
+
+func (r *memRecoveryCodes) Replace(_ context.Context, userID int64, hashes []string, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.unused == nil {
+		r.unused = map[int64][]string{}
+	}
+	r.unused[userID] = slices.Clone(hashes)
+	return nil
+}
+
+func (r *memRecoveryCodes) Use(_ context.Context, userID int64, hash string, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.Index(r.unused[userID], hash)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	r.unused[userID] = slices.Delete(r.unused[userID], i, i+1)
+	return nil
+}
+
+func (r *memRecoveryCodes) CountUnused(_ context.Context, userID int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.unused[userID]), nil
+}
+
+func (r *memRecoveryCodes) DeleteByUser(_ context.Context, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.unused, userID)
+	return nil
+}
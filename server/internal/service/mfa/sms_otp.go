@@ -0,0 +1,264 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/sms"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+var (
+	phoneRegexp = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+	// phoneSeparators are stripped from numbers before validation.
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+)
+
+// smsCodeText is the text of SMS codes per locale: the issuer, the code and its lifetime in minutes.
+var smsCodeText = map[string]string{
+	"en": "%s: your code is %s. It expires in %d minutes. Do not share it.",
+	"ru": "%s: ваш код %s. Он действует %d мин. Никому его не сообщайте.",
+}
+
+// SetPhone validates the number, stores it as pending and texts it a code.
+// The current number keeps working until the new one is verified.
+// Setting the current, verified number again is a no-op.
+func (s *service) SetPhone(ctx context.Context, req mfa.SetPhoneRequest) error {
+	const op = "service.mfa.SetPhone"
+
+	phone, err := s.normalizePhone(req.Phone)
+	if err != nil {
+		return err
+	}
+
+	u, err := s.getUser(ctx, req.UserID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if u.Phone != nil && *u.Phone == phone && u.PhoneVerifiedAt != nil {
+		return nil
+	}
+
+	// the number may receive login codes, so replacing it needs the second factor
+	if err := s.RequireStepUp(ctx, u.ID, req.MFA); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.users.SetPendingPhone(ctx, u.ID, phone); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.sendSMSCode(ctx, u.ID, phone, mfa.PurposeSetup, req.Locale, req.IP); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// VerifyPhone makes the pending number the user's phone with the code sent by SetPhone.
+func (s *service) VerifyPhone(ctx context.Context, userID int64, code string) error {
+	const op = "service.mfa.VerifyPhone"
+
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if u.PendingPhone == nil {
+		return apperr.New(http.StatusBadRequest, "no phone number to verify")
+	}
+
+	if err := s.verifyCode(ctx, u.ID, mfa.ChannelSMS, *u.PendingPhone, mfa.PurposeSetup, code, s.cfg.SMSOTP.MaxAttempts); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.users.MarkPhoneVerified(ctx, u.ID, *u.PendingPhone, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return apperr.New(http.StatusConflict, "phone number is used by another account")
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidCode
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// EnableSMSOTP turns SMS OTP on. The number was proven when it was verified.
+func (s *service) EnableSMSOTP(ctx context.Context, userID int64) error {
+	const op = "service.mfa.EnableSMSOTP"
+
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if u.PhoneVerifiedAt == nil {
+		return apperr.New(http.StatusBadRequest, "phone number is not verified")
+	}
+
+	if err := s.smsOTP.Enable(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DisableSMSOTP turns SMS OTP off. Recovery codes are removed with the last second factor.
+func (s *service) DisableSMSOTP(ctx context.Context, userID int64, stepUp *mfa.StepUp) error {
+	const op = "service.mfa.DisableSMSOTP"
+
+	if err := s.RequireStepUp(ctx, userID, stepUp); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.smsOTP.Disable(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := s.secondFactors(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !f.any() {
+		if err := s.recoveryCodes.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// SendLoginSMS texts a code for the second step of a login.
+func (s *service) SendLoginSMS(ctx context.Context, req mfa.ChallengeTokenRequest) error {
+	const op = "service.mfa.SendLoginSMS"
+
+	c, err := s.pendingChallenge(ctx, req.Token)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.sendSMSFactorCode(ctx, c.UserID, mfa.PurposeLogin, req.Locale, req.IP); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sendSMSFactorCode texts a code to the verified number of a user with SMS OTP enabled.
+func (s *service) sendSMSFactorCode(ctx context.Context, userID int64, purpose, locale, ip string) error {
+	u, enabled, err := s.smsFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return apperr.New(http.StatusBadRequest, "sms otp is not enabled")
+	}
+
+	return s.sendSMSCode(ctx, u.ID, *u.Phone, purpose, locale, ip)
+}
+
+// verifySMSCode consumes the user's SMS code for the purpose if SMS OTP is enabled and code matches it.
+func (s *service) verifySMSCode(ctx context.Context, userID int64, purpose, code string) error {
+	u, enabled, err := s.smsFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errInvalidCode
+	}
+
+	return s.verifyCode(ctx, u.ID, mfa.ChannelSMS, *u.Phone, purpose, code, s.cfg.SMSOTP.MaxAttempts)
+}
+
+// smsFactor reports whether the user has SMS OTP enabled on a verified number.
+func (s *service) smsFactor(ctx context.Context, userID int64) (*user.User, bool, error) {
+	enabled, err := s.smsOTP.IsEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return nil, false, err
+	}
+
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return u, u.Phone != nil && u.PhoneVerifiedAt != nil, nil
+}
+
+// sendSMSCode replaces the user's SMS code for the purpose with a new one and texts it.
+// Each send costs money, so numbers outside the allowed countries are refused
+// and sends are throttled per number and per client IP.
+func (s *service) sendSMSCode(ctx context.Context, userID int64, phone, purpose, locale, ip string) error {
+	cfg := s.cfg.SMSOTP
+
+	if !s.allowedCountry(phone) {
+		return apperr.New(http.StatusBadRequest, "phone numbers from this country are not supported")
+	}
+	// requests without a usable address share one bucket instead of skipping the limit
+	if addr := net.ParseIP(ip); addr != nil {
+		ip = addr.String()
+	} else {
+		ip = "unknown"
+	}
+	if !s.ipLimiter.Allow(ip) {
+		return apperr.New(http.StatusTooManyRequests, "too many codes requested, try again later")
+	}
+	if !s.phoneLimiter.Allow(phone) {
+		return apperr.New(http.StatusTooManyRequests, "too many codes sent to this number, try again later")
+	}
+
+	code, err := s.newCode(ctx, userID, mfa.ChannelSMS, phone, purpose, cfg.TTL)
+	if err != nil {
+		return err
+	}
+
+	text, ok := smsCodeText[locale]
+	if !ok {
+		text = smsCodeText["en"]
+	}
+
+	return s.sms.Send(ctx, sms.Message{
+		To:   phone,
+		Body: fmt.Sprintf(text, s.cfg.Issuer, code, int(cfg.TTL.Minutes())),
+	})
+}
+
+// normalizePhone strips separators from a number and checks it is a valid
+// E.164 number from an allowed country. A leading "00" is read as "+".
+func (s *service) normalizePhone(phone string) (string, error) {
+	phone = phoneSeparators.Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+
+	if !phoneRegexp.MatchString(phone) {
+		return "", apperr.New(http.StatusBadRequest, "invalid phone number format")
+	}
+	if !s.allowedCountry(phone) {
+		return "", apperr.New(http.StatusBadRequest, "phone numbers from this country are not supported")
+	}
+
+	return phone, nil
+}
+
+// allowedCountry reports whether an E.164 number starts with an allowed
+// calling code. Longer prefixes work too, e.g. "1416" for a single area code.
+func (s *service) allowedCountry(phone string) bool {
+	for _, code := range s.cfg.SMSOTP.AllowedCountryCodes {
+		if code != "" && strings.HasPrefix(phone, "+"+strings.TrimPrefix(code, "+")) {
+			return true
+		}
+	}
+	return false
+}
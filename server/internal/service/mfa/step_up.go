@@ -0,0 +1,87 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// BeginStepUp sends an email or SMS code, or starts a passkey ceremony for a step-up.
+// TOTP and recovery codes need no preparation.
+func (s *service) BeginStepUp(ctx context.Context, req mfa.BeginStepUpRequest) (*passkey.Options, error) {
+	const op = "service.mfa.BeginStepUp"
+
+	methods, err := s.Methods(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !slices.Contains(methods, req.Method) {
+		return nil, apperr.New(http.StatusBadRequest, "mfa method is not enabled")
+	}
+
+	switch req.Method {
+	case mfa.MethodEmailOTP:
+		if err := s.sendEmailCode(ctx, req.UserID, mfa.PurposeStepUp, req.Locale); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return nil, nil
+
+	case mfa.MethodSMSOTP:
+		if err := s.sendSMSFactorCode(ctx, req.UserID, mfa.PurposeStepUp, req.Locale, req.IP); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return nil, nil
+
+	case mfa.MethodWebAuthn:
+		raw, err := securetoken.New()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		opts, err := s.passkeys.BeginAssertion(ctx, req.UserID, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		opts.Token = raw
+		return opts, nil
+
+	default:
+		return nil, apperr.New(http.StatusBadRequest, "mfa method needs no preparation")
+	}
+}
+
+// RequireStepUp verifies stepUp if the user has a second factor.
+func (s *service) RequireStepUp(ctx context.Context, userID int64, stepUp *mfa.StepUp) error {
+	const op = "service.mfa.RequireStepUp"
+
+	methods, err := s.Methods(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if stepUp == nil || !slices.Contains(methods, stepUp.Method) {
+		return apperr.NewWithData(http.StatusUnauthorized, "step-up authentication required", mfa.StepUpChallenge{Methods: methods})
+	}
+
+	switch stepUp.Method {
+	case mfa.MethodEmailOTP:
+		err = s.verifyEmailCode(ctx, userID, mfa.PurposeStepUp, stepUp.Code)
+	case mfa.MethodSMSOTP:
+		err = s.verifySMSCode(ctx, userID, mfa.PurposeStepUp, stepUp.Code)
+	case mfa.MethodWebAuthn:
+		err = s.passkeys.FinishAssertion(ctx, userID, stepUp.Token, stepUp.Credential)
+	default:
+		err = s.Verify(ctx, userID, stepUp.Method, stepUp.Code)
+	}
+
+	return err
+}
@@ -0,0 +1,469 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// defaultCredentialName is used when the user doesn't name a new passkey.
+const defaultCredentialName = "Passkey"
+
+var (
+	errInvalidSession = apperr.New(http.StatusBadRequest, "invalid or expired passkey session")
+	errInvalidPasskey = apperr.New(http.StatusUnauthorized, "passkey verification failed")
+)
+
+// UserGetter loads the account a passkey ceremony is run for.
+type UserGetter interface {
+	GetByID(ctx context.Context, id int64) (*user.User, error)
+}
+
+type service struct {
+	credentials  passkey.CredentialRepository
+	sessions     passkey.SessionRepository
+	users        UserGetter
+	relyingParty *webauthn.WebAuthn
+	cfg          config.WebAuthn
+	logger       *slog.Logger
+}
+
+// NewService returns a new passkey service.
+func NewService(
+	credentials passkey.CredentialRepository,
+	sessions passkey.SessionRepository,
+	users UserGetter,
+	relyingParty *webauthn.WebAuthn,
+	cfg config.WebAuthn,
+	logger *slog.Logger,
+) passkey.Service {
+	return &service{
+		credentials:  credentials,
+		sessions:     sessions,
+		users:        users,
+		relyingParty: relyingParty,
+		cfg:          cfg,
+		logger:       logger,
+	}
+}
+
+// NewRelyingParty returns the WebAuthn relying party described by cfg.
+func NewRelyingParty(cfg config.WebAuthn) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    cfg.Timeout,
+		TimeoutUVD: cfg.Timeout,
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+}
+
+// BeginRegistration starts registering a new passkey for the user.
+func (s *service) BeginRegistration(ctx context.Context, userID int64) (*passkey.Options, error) {
+	const op = "service.passkey.BeginRegistration"
+
+	wu, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// ask for a discoverable credential so it can be used without entering the email
+	creation, session, err := s.relyingParty.BeginRegistration(
+		wu,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		webauthn.WithExclusions(webauthn.Credentials(wu.credentials).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw, err := securetoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.saveSession(ctx, raw, passkey.CeremonyRegistration, userID, session); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &passkey.Options{Token: raw, PublicKey: creation.Response}, nil
+}
+
+// FinishRegistration verifies the new credential and stores it.
+func (s *service) FinishRegistration(ctx context.Context, req passkey.FinishRegistrationRequest) (*passkey.Credential, error) {
+	const op = "service.passkey.FinishRegistration"
+
+	session, err := s.takeSession(ctx, req.Token, passkey.CeremonyRegistration)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if session.UserID != req.UserID {
+		return nil, errInvalidSession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		s.logRejected(op, req.UserID, err)
+		return nil, apperr.New(http.StatusBadRequest, "invalid passkey response")
+	}
+
+	wu, err := s.loadUser(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	created, err := s.relyingParty.CreateCredential(wu, *session.data, parsed)
+	if err != nil {
+		s.logRejected(op, req.UserID, err)
+		return nil, apperr.New(http.StatusBadRequest, "invalid passkey response")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = defaultCredentialName
+	}
+
+	transports := make([]string, len(created.Transport))
+	for i, t := range created.Transport {
+		transports[i] = string(t)
+	}
+
+	c := &passkey.Credential{
+		UserID:          req.UserID,
+		CredentialID:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		Transports:      transports,
+		AAGUID:          created.Authenticator.AAGUID,
+		SignCount:       created.Authenticator.SignCount,
+		Flags:           uint8(parsed.Response.AttestationObject.AuthData.Flags),
+		Name:            name,
+		CreatedAt:       time.Now(),
+	}
+	c.ID, err = s.credentials.Save(ctx, c)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, apperr.New(http.StatusConflict, "passkey is already registered")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// BeginLogin starts a login where the authenticator picks the account.
+// User verification is required, so the passkey alone is enough to log in.
+func (s *service) BeginLogin(ctx context.Context) (*passkey.Options, error) {
+	const op = "service.passkey.BeginLogin"
+
+	assertion, session, err := s.relyingParty.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw, err := securetoken.New()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.saveSession(ctx, raw, passkey.CeremonyLogin, 0, session); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &passkey.Options{Token: raw, PublicKey: assertion.Response}, nil
+}
+
+// FinishLogin verifies a discoverable login and returns the user it belongs to.
+func (s *service) FinishLogin(ctx context.Context, req passkey.FinishLoginRequest) (int64, error) {
+	const op = "service.passkey.FinishLogin"
+
+	session, err := s.takeSession(ctx, req.Token, passkey.CeremonyLogin)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		s.logRejected(op, 0, err)
+		return 0, errInvalidPasskey
+	}
+
+	// the authenticator tells which credential it used, and the credential which user
+	var wu *webauthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		c, err := s.credentials.GetByCredentialID(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(userHandle, newUserHandle(c.UserID)) {
+			return nil, errors.New("user handle does not match the credential")
+		}
+		wu, err = s.loadUser(ctx, c.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return wu, nil
+	}
+
+	_, validated, err := s.relyingParty.ValidatePasskeyLogin(handler, *session.data, parsed)
+	if err != nil {
+		s.logRejected(op, 0, err)
+		return 0, errInvalidPasskey
+	}
+
+	if err := s.recordUsage(ctx, op, wu, validated, parsed); err != nil {
+		return 0, err
+	}
+
+	return wu.user.ID, nil
+}
+
+// BeginAssertion starts verifying one of the user's passkeys as a second factor.
+func (s *service) BeginAssertion(ctx context.Context, userID int64, token string) (*passkey.Options, error) {
+	const op = "service.passkey.BeginAssertion"
+
+	wu, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(wu.credentials) == 0 {
+		return nil, apperr.New(http.StatusBadRequest, "no passkeys registered")
+	}
+
+	assertion, session, err := s.relyingParty.BeginLogin(wu)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.saveSession(ctx, token, passkey.CeremonyMFA, userID, session); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &passkey.Options{PublicKey: assertion.Response}, nil
+}
+
+// FinishAssertion verifies the user's passkey for a ceremony begun with BeginAssertion.
+func (s *service) FinishAssertion(ctx context.Context, userID int64, token string, credential json.RawMessage) error {
+	const op = "service.passkey.FinishAssertion"
+
+	session, err := s.takeSession(ctx, token, passkey.CeremonyMFA)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if session.UserID != userID {
+		return errInvalidSession
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		s.logRejected(op, userID, err)
+		return errInvalidPasskey
+	}
+
+	wu, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	validated, err := s.relyingParty.ValidateLogin(wu, *session.data, parsed)
+	if err != nil {
+		s.logRejected(op, userID, err)
+		return errInvalidPasskey
+	}
+
+	return s.recordUsage(ctx, op, wu, validated, parsed)
+}
+
+// ListCredentials returns the passkeys of the user.
+func (s *service) ListCredentials(ctx context.Context, userID int64) ([]passkey.Credential, error) {
+	const op = "service.passkey.ListCredentials"
+
+	credentials, err := s.credentials.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return credentials, nil
+}
+
+// DeleteCredential removes a passkey of the user.
+func (s *service) DeleteCredential(ctx context.Context, userID, id int64) error {
+	const op = "service.passkey.DeleteCredential"
+
+	if err := s.credentials.Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.New(http.StatusNotFound, "passkey not found")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// recordUsage stores the new sign count of a credential after a successful
+// assertion. A counter that went backwards means the credential may have been cloned.
+func (s *service) recordUsage(ctx context.Context, op string, wu *webauthnUser, validated *webauthn.Credential, parsed *protocol.ParsedCredentialAssertionData) error {
+	c := wu.find(validated.ID)
+	if c == nil {
+		return errInvalidPasskey
+	}
+
+	if validated.Authenticator.CloneWarning {
+		s.logger.Warn("passkey sign count went backwards, possible cloned authenticator", slog.String("op", op), slog.Int64("user_id", c.UserID), slog.Int64("credential", c.ID))
+		return errInvalidPasskey
+	}
+
+	flags := uint8(parsed.Response.AuthenticatorData.Flags)
+	if err := s.credentials.UpdateUsage(ctx, c.ID, validated.Authenticator.SignCount, flags, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// session is a stored ceremony with its decoded WebAuthn state.
+type session struct {
+	*passkey.Session
+	data *webauthn.SessionData
+}
+
+func (s *service) saveSession(ctx context.Context, token, ceremony string, userID int64, data *webauthn.SessionData) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.sessions.Save(ctx, &passkey.Session{
+		TokenHash: securetoken.Hash(token),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		Data:      raw,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.Timeout),
+	})
+}
+
+// takeSession consumes the session of a ceremony, so each one can be finished only once.
+func (s *service) takeSession(ctx context.Context, token, ceremony string) (*session, error) {
+	stored, err := s.sessions.Take(ctx, securetoken.Hash(token), ceremony)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidSession
+		}
+		return nil, err
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return nil, errInvalidSession
+	}
+
+	var data webauthn.SessionData
+	if err := json.Unmarshal(stored.Data, &data); err != nil {
+		return nil, err
+	}
+
+	return &session{Session: stored, data: &data}, nil
+}
+
+func (s *service) loadUser(ctx context.Context, userID int64) (*webauthnUser, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(http.StatusNotFound, "user not found")
+		}
+		return nil, err
+	}
+
+	stored, err := s.credentials.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWebAuthnUser(u, stored), nil
+}
+
+// logRejected records why a ceremony failed. Clients only get a generic error.
+func (s *service) logRejected(op string, userID int64, err error) {
+	attrs := []any{slog.String("op", op), slog.Int64("user_id", userID), slog.String("err", err.Error())}
+	var perr *protocol.Error
+	if errors.As(err, &perr) {
+		attrs = append(attrs, slog.String("details", perr.Details), slog.String("info", perr.DevInfo))
+	}
+	s.logger.Info("passkey ceremony rejected", attrs...)
+}
+
+// webauthnUser adapts a user and their stored credentials to webauthn.User.
+type webauthnUser struct {
+	user        *user.User
+	stored      []passkey.Credential
+	credentials []webauthn.Credential
+}
+
+func newWebAuthnUser(u *user.User, stored []passkey.Credential) *webauthnUser {
+	credentials := make([]webauthn.Credential, len(stored))
+	for i, c := range stored {
+		transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+		for j, t := range c.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+
+		credentials[i] = webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		}
+	}
+
+	return &webauthnUser{user: u, stored: stored, credentials: credentials}
+}
+
+func (u *webauthnUser) WebAuthnID() []byte                         { return newUserHandle(u.user.ID) }
+func (u *webauthnUser) WebAuthnName() string                       { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string                { return u.user.Username }
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+func (u *webauthnUser) find(credentialID []byte) *passkey.Credential {
+	for i := range u.stored {
+		if bytes.Equal(u.stored[i].CredentialID, credentialID) {
+			return &u.stored[i]
+		}
+	}
+	return nil
+}
+
+// newUserHandle returns the WebAuthn user handle of a user. It is the
+// big-endian user ID, which is stable and contains no personal data.
+func newUserHandle(userID int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
@@ -0,0 +1,515 @@
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+func TestRegistrationAndDiscoverableLogin(t *testing.T) {
+	ctx := context.Background()
+	svc, credentials := newTestService(t)
+	auth := newAuthenticator(t, 42)
+
+	cred := register(t, svc, auth)
+	if cred.Name != "Laptop" || !bytes.Equal(cred.CredentialID, auth.credentialID) {
+		t.Fatalf("unexpected credential %+v", cred)
+	}
+	if got, _ := credentials.ListByUser(ctx, 42); len(got) != 1 {
+		t.Fatalf("stored %d credentials, want 1", len(got))
+	}
+
+	opts, err := svc.BeginLogin(ctx)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	userID, err := svc.FinishLogin(ctx, passkey.FinishLoginRequest{
+		Token:      opts.Token,
+		Credential: auth.assert(t, challengeOf(t, opts), testOrigin),
+	})
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if userID != 42 {
+		t.Fatalf("logged in as user %d, want 42", userID)
+	}
+
+	stored, _ := credentials.ListByUser(ctx, 42)
+	if stored[0].SignCount != auth.signCount || stored[0].LastUsedAt == nil {
+		t.Fatalf("usage not recorded: %+v", stored[0])
+	}
+
+	// the ceremony can only be finished once
+	_, err = svc.FinishLogin(ctx, passkey.FinishLoginRequest{
+		Token:      opts.Token,
+		Credential: auth.assert(t, challengeOf(t, opts), testOrigin),
+	})
+	if !errors.Is(err, errInvalidSession) {
+		t.Fatalf("replayed FinishLogin: got %v, want %v", err, errInvalidSession)
+	}
+}
+
+func TestSecondFactorAssertion(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+	auth := newAuthenticator(t, 42)
+	register(t, svc, auth)
+
+	const mfaToken = "mfa-token"
+
+	opts, err := svc.BeginAssertion(ctx, 42, mfaToken)
+	if err != nil {
+		t.Fatalf("BeginAssertion: %v", err)
+	}
+	challenge := challengeOf(t, opts)
+
+	// the ceremony is bound to the MFA token it was started for
+	err = svc.FinishAssertion(ctx, 42, "other-token", auth.assert(t, challenge, testOrigin))
+	if !errors.Is(err, errInvalidSession) {
+		t.Fatalf("FinishAssertion with another token: got %v, want %v", err, errInvalidSession)
+	}
+
+	if err := svc.FinishAssertion(ctx, 42, mfaToken, auth.assert(t, challenge, testOrigin)); err != nil {
+		t.Fatalf("FinishAssertion: %v", err)
+	}
+
+	// and to the user of that token
+	if _, err := svc.BeginAssertion(ctx, 42, mfaToken); err != nil {
+		t.Fatalf("BeginAssertion: %v", err)
+	}
+	err = svc.FinishAssertion(ctx, 7, mfaToken, auth.assert(t, challenge, testOrigin))
+	if !errors.Is(err, errInvalidSession) {
+		t.Fatalf("FinishAssertion for another user: got %v, want %v", err, errInvalidSession)
+	}
+}
+
+func TestAssertionRejectsWrongChallengeOrOrigin(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService(t)
+	auth := newAuthenticator(t, 42)
+	register(t, svc, auth)
+
+	tests := []struct {
+		name      string
+		challenge func(issued []byte) []byte
+		origin    string
+	}{
+		{
+			name:      "wrong challenge",
+			challenge: func([]byte) []byte { return bytes.Repeat([]byte{1}, 32) },
+			origin:    testOrigin,
+		},
+		{
+			name:      "wrong origin",
+			challenge: func(issued []byte) []byte { return issued },
+			origin:    "https://evil.example",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := svc.BeginLogin(ctx)
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+
+			_, err = svc.FinishLogin(ctx, passkey.FinishLoginRequest{
+				Token:      opts.Token,
+				Credential: auth.assert(t, tt.challenge(challengeOf(t, opts)), tt.origin),
+			})
+			if !errors.Is(err, errInvalidPasskey) {
+				t.Fatalf("got %v, want %v", err, errInvalidPasskey)
+			}
+		})
+	}
+}
+
+func TestAssertionRejectsSignCountGoingBackwards(t *testing.T) {
+	ctx := context.Background()
+	svc, credentials := newTestService(t)
+	auth := newAuthenticator(t, 42)
+	register(t, svc, auth)
+
+	login := func() error {
+		opts, err := svc.BeginLogin(ctx)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err)
+		}
+		_, err = svc.FinishLogin(ctx, passkey.FinishLoginRequest{
+			Token:      opts.Token,
+			Credential: auth.assert(t, challengeOf(t, opts), testOrigin),
+		})
+		return err
+	}
+
+	if err := login(); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	stored, _ := credentials.ListByUser(ctx, 42)
+	highest := stored[0].SignCount
+
+	// a clone of the authenticator reports a counter the server has already seen
+	auth.signCount = highest - 1
+	if err := login(); !errors.Is(err, errInvalidPasskey) {
+		t.Fatalf("cloned login: got %v, want %v", err, errInvalidPasskey)
+	}
+
+	stored, _ = credentials.ListByUser(ctx, 42)
+	if stored[0].SignCount != highest {
+		t.Fatalf("sign count changed to %d after a rejected login, want %d", stored[0].SignCount, highest)
+	}
+}
+
+func newTestService(t *testing.T) (passkey.Service, *memCredentials) {
+	t.Helper()
+
+	cfg := config.WebAuthn{
+		RPID:          testRPID,
+		RPDisplayName: "AuthX",
+		RPOrigins:     []string{testOrigin},
+		Timeout:       time.Minute,
+	}
+	rp, err := NewRelyingParty(cfg)
+	if err != nil {
+		t.Fatalf("NewRelyingParty: %v", err)
+	}
+
+	credentials := &memCredentials{}
+	users := memUsers{
+		42: {ID: 42, Email: "alice@example.com", Username: "alice"},
+		7:  {ID: 7, Email: "bob@example.com", Username: "bob"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return NewService(credentials, &memSessions{}, users, rp, cfg, logger), credentials
+}
+
+// register runs a registration ceremony for the authenticator's user.
+func register(t *testing.T, svc passkey.Service, auth *authenticator) *passkey.Credential {
+	t.Helper()
+	ctx := context.Background()
+
+	opts, err := svc.BeginRegistration(ctx, auth.userID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+
+	cred, err := svc.FinishRegistration(ctx, passkey.FinishRegistrationRequest{
+		UserID:     auth.userID,
+		Token:      opts.Token,
+		Name:       "Laptop",
+		Credential: auth.create(t, challengeOf(t, opts)),
+	})
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	return cred
+}
+
+// challengeOf returns the challenge of the options as sent to the browser.
+func challengeOf(t *testing.T, opts *passkey.Options) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"public_key"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(decoded.PublicKey.Challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+// authenticator is a software platform authenticator holding one discoverable
+// ES256 credential. It does no attestation ("none" format).
+type authenticator struct {
+	userID       int64
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newAuthenticator(t *testing.T, userID int64) *authenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &authenticator{userID: userID, credentialID: credentialID, key: key, signCount: 1}
+}
+
+// create answers navigator.credentials.create().
+func (a *authenticator) create(t *testing.T, challenge []byte) json.RawMessage {
+	t.Helper()
+
+	clientData := clientDataJSON(t, "webauthn.create", challenge, testOrigin)
+
+	// attested credential data: aaguid, credential ID length, credential ID, COSE key
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, a.coseKey(t)...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(flagUserPresent|flagUserVerified|flagAttestedData, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]any{
+		"clientDataJSON":    b64(clientData),
+		"attestationObject": b64(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// assert answers navigator.credentials.get(), bumping the sign count.
+func (a *authenticator) assert(t *testing.T, challenge []byte, origin string) json.RawMessage {
+	t.Helper()
+
+	a.signCount++
+	clientData := clientDataJSON(t, "webauthn.get", challenge, origin)
+	authData := a.authData(flagUserPresent|flagUserVerified, nil)
+
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credentialJSON(t, a.credentialID, map[string]any{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(newUserHandle(a.userID)),
+	})
+}
+
+func (a *authenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// coseKey encodes the public key as a COSE_Key (RFC 9053): EC2, ES256, P-256.
+func (a *authenticator) coseKey(t *testing.T) []byte {
+	t.Helper()
+
+	pub, err := a.key.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := pub.Bytes() // 0x04 || x || y
+
+	key, err := webauthncbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: point[1:33],
+		-3: point[33:],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge []byte, origin string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": b64(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func credentialJSON(t *testing.T, id []byte, response map[string]any) json.RawMessage {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{
+		"id":       b64(id),
+		"rawId":    b64(id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type memUsers map[int64]*user.User
+
+func (m memUsers) GetByID(_ context.Context, id int64) (*user.User, error) {
+	u, ok := m[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+type memCredentials struct {
+	mu          sync.Mutex
+	credentials []passkey.Credential
+}
+
+func (m *memCredentials) Save(_ context.Context, c *passkey.Credential) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.credentials {
+		if bytes.Equal(existing.CredentialID, c.CredentialID) {
+			return 0, repository.ErrConflict
+		}
+	}
+	id := int64(len(m.credentials) + 1)
+	stored := *c
+	stored.ID = id
+	m.credentials = append(m.credentials, stored)
+	return id, nil
+}
+
+func (m *memCredentials) ListByUser(_ context.Context, userID int64) ([]passkey.Credential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []passkey.Credential
+	for _, c := range m.credentials {
+		if c.UserID == userID {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (m *memCredentials) GetByCredentialID(_ context.Context, credentialID []byte) (*passkey.Credential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (m *memCredentials) UpdateUsage(_ context.Context, id int64, signCount uint32, flags uint8, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.credentials {
+		if m.credentials[i].ID == id {
+			m.credentials[i].SignCount = signCount
+			m.credentials[i].Flags = flags
+			m.credentials[i].LastUsedAt = &at
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (m *memCredentials) Delete(_ context.Context, userID, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, c := range m.credentials {
+		if c.ID == id && c.UserID == userID {
+			m.credentials = append(m.credentials[:i], m.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+type memSessions struct {
+	mu       sync.Mutex
+	sessions map[string]passkey.Session
+}
+
+func (m *memSessions) Save(_ context.Context, s *passkey.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sessions == nil {
+		m.sessions = make(map[string]passkey.Session)
+	}
+	m.sessions[s.TokenHash] = *s
+	return nil
+}
+
+func (m *memSessions) Take(_ context.Context, hash, ceremony string) (*passkey.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[hash]
+	if !ok || s.Ceremony != ceremony {
+		return nil, repository.ErrNotFound
+	}
+	delete(m.sessions, hash)
+	return &s, nil
+}
@@ -0,0 +1,124 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/session"
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+type service struct {
+	sessions     session.Repository
+	tokenService token.Service
+	logger       *slog.Logger
+
+	mu       sync.Mutex
+	activity map[string]activity
+}
+
+// activity is the latest unsaved use of a session.
+type activity struct {
+	ip string
+	at time.Time
+}
+
+// NewService returns a new session service.
+func NewService(sessions session.Repository, tokenService token.Service, logger *slog.Logger) session.Service {
+	return &service{
+		sessions:     sessions,
+		tokenService: tokenService,
+		logger:       logger,
+		activity:     make(map[string]activity),
+	}
+}
+
+// List returns the active sessions of the user.
+func (s *service) List(ctx context.Context, userID int64, currentID string) ([]session.Session, error) {
+	const op = "service.session.List"
+
+	sessions, err := s.sessions.ListActive(ctx, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends a session of the user: its refresh tokens are revoked and its
+// access tokens denylisted by their sid claim.
+func (s *service) Revoke(ctx context.Context, req session.RevokeRequest) error {
+	const op = "service.session.Revoke"
+
+	sess, err := s.sessions.Get(ctx, req.UserID, req.SessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.New(http.StatusNotFound, "session not found")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if sess.RevokedAt != nil {
+		return apperr.New(http.StatusNotFound, "session not found")
+	}
+
+	err = s.tokenService.Revoke(ctx, token.RevokeRequest{
+		UserID:    req.UserID,
+		SessionID: sess.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RecordActivity keeps the latest use of the session in memory. Requests in
+// the same flush interval cost one write between them.
+func (s *service) RecordActivity(id, ip string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.activity[id]; ok && a.at.After(at) {
+		return
+	}
+	s.activity[id] = activity{ip: session.NormalizeIP(ip), at: at}
+}
+
+// FlushActivity writes the recorded activity to the repository. Activity is
+// best effort: a session that fails to save is logged and dropped, so one bad
+// entry can't hold back the others, and its next request records it again.
+func (s *service) FlushActivity(ctx context.Context) (int, error) {
+	const op = "service.session.FlushActivity"
+
+	s.mu.Lock()
+	pending := s.activity
+	s.activity = make(map[string]activity, len(pending))
+	s.mu.Unlock()
+
+	n := 0
+	for id, a := range pending {
+		if err := ctx.Err(); err != nil {
+			return n, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err := s.sessions.Touch(ctx, id, a.ip, a.at); err != nil {
+			s.logger.Warn("failed to save session activity", slog.String("op", op), slog.String("session_id", id), slog.String("err", err.Error()))
+			continue
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,439 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/session"
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/jwt"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/lib/useragent"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// AccessIssuer mints signed access tokens.
+type AccessIssuer interface {
+	Issue(sub jwt.Subject) (string, *jwt.Claims, error)
+	TTL() time.Duration
+	Leeway() time.Duration
+}
+
+// UserGetter loads the user an access token is issued to.
+type UserGetter interface {
+	GetByID(ctx context.Context, id int64) (*user.User, error)
+}
+
+type service struct {
+	tokenRepo    token.Repository
+	sessions     session.Repository
+	denylist     token.Denylist
+	users        UserGetter
+	accessIssuer AccessIssuer
+	cfg          config.RefreshToken
+	sessionCfg   config.Session
+	logger       *slog.Logger
+}
+
+// NewService returns a new token service.
+func NewService(tokenRepo token.Repository, sessions session.Repository, denylist token.Denylist, users UserGetter, accessIssuer AccessIssuer, cfg config.RefreshToken, sessionCfg config.Session, logger *slog.Logger) token.Service {
+	return &service{
+		tokenRepo:    tokenRepo,
+		sessions:     sessions,
+		denylist:     denylist,
+		users:        users,
+		accessIssuer: accessIssuer,
+		cfg:          cfg,
+		sessionCfg:   sessionCfg,
+		logger:       logger,
+	}
+}
+
+// Bounds of the device details stored with a session.
+const (
+	maxUserAgentLength = 512
+	maxDeviceLength    = 64 // browser and os
+)
+
+var (
+	errInvalidRefreshToken = apperr.New(http.StatusUnauthorized, "invalid refresh token")
+	errSessionEnded        = apperr.New(http.StatusUnauthorized, "session has ended")
+)
+
+// Issue starts a new session for the user. The session ID doubles as the
+// refresh token family ID and the sid claim, so revoking it ends both.
+func (s *service) Issue(ctx context.Context, req token.IssueRequest) (*token.Pair, error) {
+	const op = "service.token.Issue"
+
+	familyID, err := securetoken.NewN(16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	policy := s.sessionCfg.Policy(req.RememberMe)
+	familyExpiresAt := now.Add(policy.AbsoluteLifetime)
+
+	userAgent := truncate(req.Client.UserAgent, maxUserAgentLength)
+	device := useragent.Parse(userAgent)
+	err = s.sessions.Save(ctx, &session.Session{
+		ID:          familyID,
+		UserID:      req.UserID,
+		UserAgent:   userAgent,
+		Browser:     truncate(device.Browser, maxDeviceLength),
+		OS:          truncate(device.OS, maxDeviceLength),
+		Device:      device.Device,
+		IP:          session.NormalizeIP(req.Client.IP),
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   familyExpiresAt,
+		RememberMe:  req.RememberMe,
+		IdleTimeout: policy.IdleTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw, rt, err := s.newRefreshToken(req.UserID, familyID, req.Auth, now, policy.IdleTimeout, familyExpiresAt, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.tokenRepo.Save(ctx, rt); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.pair(ctx, rt, raw)
+}
+
+// Reauthenticate rotates the session's current refresh token into one that
+// carries the new authentication, so refreshed access tokens keep it.
+// The client must replace its refresh token with the returned one.
+func (s *service) Reauthenticate(ctx context.Context, userID int64, sessionID string, auth token.Authentication) (*token.Pair, error) {
+	const op = "service.token.Reauthenticate"
+
+	current, err := s.tokenRepo.GetActiveByFamily(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errSessionEnded
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if current.UserID != userID || !now.Before(current.ExpiresAt) || !now.Before(current.FamilyExpiresAt) {
+		return nil, errSessionEnded
+	}
+
+	sess, err := s.activeSession(ctx, userID, sessionID, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw, next, err := s.newRefreshToken(userID, current.FamilyID, auth, now, sess.IdleTimeout, current.FamilyExpiresAt, current.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.tokenRepo.Rotate(ctx, current.ID, next); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			// the session was refreshed or revoked meanwhile
+			return nil, errSessionEnded
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.pair(ctx, next, raw)
+}
+
+// Refresh rotates the refresh token. Presenting a token that was already
+// rotated revokes its whole family (OAuth 2.0 Security BCP, refresh token reuse detection).
+func (s *service) Refresh(ctx context.Context, refreshToken string, client session.Client) (*token.Pair, error) {
+	const op = "service.token.Refresh"
+
+	current, err := s.tokenRepo.GetByHash(ctx, securetoken.Hash(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+
+	if current.RevokedAt != nil {
+		return nil, errInvalidRefreshToken
+	}
+	if current.RotatedAt != nil {
+		return nil, s.reuseDetected(ctx, current, now)
+	}
+	if !now.Before(current.ExpiresAt) || !now.Before(current.FamilyExpiresAt) {
+		return nil, errInvalidRefreshToken
+	}
+
+	sess, err := s.activeSession(ctx, current.UserID, current.FamilyID, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	raw, next, err := s.newRefreshToken(current.UserID, current.FamilyID, current.Auth, now, sess.IdleTimeout, current.FamilyExpiresAt, current.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := s.tokenRepo.Rotate(ctx, current.ID, next); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			// Lost a race against another rotation of the same token.
+			return nil, s.reuseDetected(ctx, current, now)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Refreshing is activity too, and it already costs a write, so it is
+	// recorded right away rather than coalesced like other requests.
+	if err := s.sessions.Touch(ctx, sess.ID, session.NormalizeIP(client.IP), now); err != nil {
+		s.logger.Warn("failed to touch session", slog.String("op", op), slog.String("session_id", sess.ID), slog.String("err", err.Error()))
+	}
+
+	return s.pair(ctx, next, raw)
+}
+
+// Revoke ends the session the access token belongs to.
+func (s *service) Revoke(ctx context.Context, req token.RevokeRequest) error {
+	const op = "service.token.Revoke"
+
+	now := time.Now()
+
+	if req.SessionID != "" {
+		if err := s.tokenRepo.RevokeFamily(ctx, req.SessionID, now); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := s.revokeSessions(ctx, now, req.SessionID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := s.revokeAccessToken(ctx, req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeAll ends every session of the user.
+func (s *service) RevokeAll(ctx context.Context, req token.RevokeRequest) error {
+	const op = "service.token.RevokeAll"
+
+	now := time.Now()
+
+	families, err := s.tokenRepo.RevokeByUser(ctx, req.UserID, "", now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if req.SessionID != "" {
+		families = append(families, req.SessionID)
+	}
+
+	if err := s.revokeSessions(ctx, now, families...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.revokeAccessToken(ctx, req); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeOthers ends every session of the user but the current one.
+func (s *service) RevokeOthers(ctx context.Context, req token.RevokeRequest) error {
+	const op = "service.token.RevokeOthers"
+
+	now := time.Now()
+
+	families, err := s.tokenRepo.RevokeByUser(ctx, req.UserID, req.SessionID, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.revokeSessions(ctx, now, families...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the access token or its session has been revoked.
+func (s *service) IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error) {
+	const op = "service.token.IsRevoked"
+
+	keys := []string{token.AccessTokenKey(tokenID)}
+	if sessionID != "" {
+		keys = append(keys, token.SessionKey(sessionID))
+	}
+
+	revoked, err := s.denylist.Contains(ctx, keys...)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+// PurgeExpired deletes denylist entries that no longer need to be kept.
+func (s *service) PurgeExpired(ctx context.Context) (int64, error) {
+	const op = "service.token.PurgeExpired"
+
+	n, err := s.denylist.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// revokeAccessToken denylists the access token until verifiers stop accepting it.
+func (s *service) revokeAccessToken(ctx context.Context, req token.RevokeRequest) error {
+	if req.TokenID == "" {
+		return nil
+	}
+	return s.denylist.Add(ctx, token.AccessTokenKey(req.TokenID), req.TokenExpiresAt.Add(s.accessIssuer.Leeway()))
+}
+
+// revokeSessions ends the given sessions and denylists every access token of them.
+// Access tokens issued before now expire within the access token TTL, so that's how long entries are kept.
+func (s *service) revokeSessions(ctx context.Context, now time.Time, sessionIDs ...string) error {
+	if err := s.sessions.Revoke(ctx, now, sessionIDs...); err != nil {
+		return err
+	}
+
+	expiresAt := now.Add(s.accessIssuer.TTL() + s.accessIssuer.Leeway())
+	for _, id := range sessionIDs {
+		if err := s.denylist.Add(ctx, token.SessionKey(id), expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reuseDetected revokes the family of a refresh token that was presented after rotation.
+func (s *service) reuseDetected(ctx context.Context, rt *token.RefreshToken, now time.Time) error {
+	const op = "service.token.reuseDetected"
+
+	s.logger.Warn("refresh token reuse detected, revoking token family",
+		slog.String("op", op),
+		slog.Int64("user_id", rt.UserID),
+		slog.String("family_id", rt.FamilyID),
+	)
+
+	if err := s.tokenRepo.RevokeFamily(ctx, rt.FamilyID, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.revokeSessions(ctx, now, rt.FamilyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return errInvalidRefreshToken
+}
+
+// activeSession returns the session a refresh token belongs to, or
+// errSessionEnded if it was revoked, has expired or has been idle too long.
+func (s *service) activeSession(ctx context.Context, userID int64, id string, now time.Time) (*session.Session, error) {
+	sess, err := s.sessions.Get(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errSessionEnded
+		}
+		return nil, err
+	}
+	if !sess.Active(now) {
+		return nil, errSessionEnded
+	}
+	return sess, nil
+}
+
+// newRefreshToken generates a refresh token of the given family that expires
+// after ttl, or with the family when ttl is zero, as for sessions from before
+// idle timeouts were recorded. prevExpiresAt is the expiry of the token being
+// rotated, or zero for a new family.
+func (s *service) newRefreshToken(userID int64, familyID string, auth token.Authentication, now time.Time, ttl time.Duration, familyExpiresAt, prevExpiresAt time.Time) (string, *token.RefreshToken, error) {
+	raw, err := securetoken.New()
+	if err != nil {
+		return "", nil, err
+	}
+
+	expiresAt := familyExpiresAt
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
+	}
+	if !s.cfg.Sliding && !prevExpiresAt.IsZero() {
+		expiresAt = prevExpiresAt
+	}
+	if expiresAt.After(familyExpiresAt) {
+		expiresAt = familyExpiresAt
+	}
+
+	return raw, &token.RefreshToken{
+		UserID:          userID,
+		FamilyID:        familyID,
+		TokenHash:       securetoken.Hash(raw),
+		CreatedAt:       now,
+		ExpiresAt:       expiresAt,
+		FamilyExpiresAt: familyExpiresAt,
+		Auth:            auth,
+	}, nil
+}
+
+// pair issues an access token bound to the token family of rt and combines it with the raw refresh token.
+// The user is reloaded so that claims such as email_verified are current on every refresh.
+func (s *service) pair(ctx context.Context, rt *token.RefreshToken, refreshToken string) (*token.Pair, error) {
+	const op = "service.token.pair"
+
+	u, err := s.users.GetByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	access, claims, err := s.accessIssuer.Issue(jwt.Subject{
+		UserID:        rt.UserID,
+		SessionID:     rt.FamilyID,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		AuthTime:      rt.Auth.Time,
+		AMR:           rt.Auth.Methods,
+		ACR:           rt.Auth.ACR(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &token.Pair{
+		AccessToken:      access,
+		AccessExpiresAt:  claims.ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: rt.ExpiresAt,
+		SessionID:        rt.FamilyID,
+	}, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
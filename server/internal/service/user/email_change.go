@@ -0,0 +1,180 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/password"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+var errInvalidEmailChangeToken = apperr.New(http.StatusBadRequest, "invalid or expired email change token")
+
+// ChangeEmail sends a confirmation link to the new address and a notice with
+// a revert link to the current one. The email is only changed on confirmation.
+func (s *service) ChangeEmail(ctx context.Context, req user.ChangeEmailRequest) error {
+	const op = "service.user.ChangeEmail"
+
+	newEmail := strings.ToLower(strings.TrimSpace(req.NewEmail))
+	if !emailRegexp.MatchString(newEmail) {
+		return apperr.New(http.StatusBadRequest, "invalid email format")
+	}
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.New(http.StatusNotFound, "user not found")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if newEmail == u.Email {
+		return apperr.New(http.StatusBadRequest, "new email must differ from the current one")
+	}
+
+	// check password
+	if _, err := s.passwordHasher.Verify(req.Password, u.Password); err != nil {
+		if errors.Is(err, password.ErrMismatch) {
+			return apperr.New(http.StatusBadRequest, "invalid password")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	confirmToken, err := securetoken.New()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	revertToken, err := securetoken.New()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// replace any pending change
+	if err := s.emailChanges.DeletePending(ctx, u.ID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	cfg := s.cfg.EmailChange
+	now := time.Now()
+	_, err = s.emailChanges.Save(ctx, &user.EmailChange{
+		UserID:           u.ID,
+		OldEmail:         u.Email,
+		NewEmail:         newEmail,
+		ConfirmTokenHash: securetoken.Hash(confirmToken),
+		RevertTokenHash:  securetoken.Hash(revertToken),
+		CreatedAt:        now,
+		ConfirmExpiresAt: now.Add(cfg.ConfirmTTL),
+		RevertExpiresAt:  now.Add(cfg.RevertTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// send confirmation to the new address
+	confirmLink, err := withQuery(cfg.ConfirmURL, "token", confirmToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = s.sendMail(ctx, newEmail, req.Locale, "email_change_confirm", map[string]any{
+		"Username":       u.Username,
+		"NewEmail":       newEmail,
+		"Link":           confirmLink,
+		"ExpiresInHours": int(cfg.ConfirmTTL.Hours()),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// notify the old address
+	revertLink, err := withQuery(cfg.RevertURL, "token", revertToken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = s.sendMail(ctx, u.Email, req.Locale, "email_change_notice", map[string]any{
+		"Username":      u.Username,
+		"NewEmail":      newEmail,
+		"Link":          revertLink,
+		"ExpiresInDays": int(cfg.RevertTTL.Hours() / 24),
+	})
+	if err != nil {
+		s.logger.Error("failed to send email change notice", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches the user to the new, now verified, address.
+func (s *service) ConfirmEmailChange(ctx context.Context, req user.EmailChangeTokenRequest) error {
+	const op = "service.user.ConfirmEmailChange"
+
+	c, err := s.emailChanges.GetByConfirmHash(ctx, securetoken.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidEmailChangeToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if c.ConfirmedAt != nil || c.RevertedAt != nil || !now.Before(c.ConfirmExpiresAt) {
+		return errInvalidEmailChangeToken
+	}
+
+	if err := s.emailChanges.Confirm(ctx, c.ID, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return errInvalidEmailChangeToken
+		case errors.Is(err, repository.ErrConflict):
+			return apperr.New(http.StatusConflict, "email is already in use")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevertEmailChange cancels or undoes an email change from the old address.
+// Every session is ended, since the change may have been made by someone
+// who took over the account.
+func (s *service) RevertEmailChange(ctx context.Context, req user.EmailChangeTokenRequest) error {
+	const op = "service.user.RevertEmailChange"
+
+	c, err := s.emailChanges.GetByRevertHash(ctx, securetoken.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidEmailChangeToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if c.RevertedAt != nil || !now.Before(c.RevertExpiresAt) {
+		return errInvalidEmailChangeToken
+	}
+
+	if err := s.emailChanges.Revert(ctx, c.ID, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return errInvalidEmailChangeToken
+		case errors.Is(err, repository.ErrConflict):
+			return apperr.New(http.StatusConflict, "email is already in use")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.tokenService.RevokeAll(ctx, token.RevokeRequest{UserID: c.UserID}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// Email verification modes.
+const (
+	EmailVerificationFlag  = "flag"
+	EmailVerificationBlock = "block"
+)
+
+// purposeVerifyEmail is the signed token purpose of verification links.
+const purposeVerifyEmail = "verify_email"
+
+var errInvalidVerificationToken = apperr.New(http.StatusBadRequest, "invalid or expired verification token")
+
+// VerifyEmail marks the email a verification link was sent to as verified.
+// Links sent to a previous address of the user are rejected.
+func (s *service) VerifyEmail(ctx context.Context, req user.VerifyEmailRequest) error {
+	const op = "service.user.VerifyEmail"
+
+	claims, err := s.linkSigner.Verify(req.Token, purposeVerifyEmail)
+	if err != nil {
+		return errInvalidVerificationToken
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, claims.Subject, claims.Value, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidVerificationToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new verification link. Requests for the same
+// address are limited to one per resend interval.
+func (s *service) ResendVerification(ctx context.Context, req user.ResendVerificationRequest) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !emailRegexp.MatchString(email) {
+		return apperr.New(http.StatusBadRequest, "invalid email format")
+	}
+
+	if !s.resendLimiter.Allow(email) {
+		return apperr.New(http.StatusTooManyRequests, "verification email was sent recently")
+	}
+
+	s.background(ctx, func(ctx context.Context) {
+		const op = "service.user.ResendVerification"
+
+		u, err := s.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			if !errors.Is(err, repository.ErrNotFound) {
+				s.logger.Error("failed to get user", slog.String("op", op), slog.String("err", err.Error()))
+			}
+			return
+		}
+		if u.EmailVerifiedAt != nil {
+			return
+		}
+
+		s.sendVerificationEmail(ctx, u, req.Locale)
+	})
+
+	return nil
+}
+
+// sendVerificationEmail mails a signed verification link bound to the user's current email.
+// Failures are logged only: the caller has already answered the request.
+func (s *service) sendVerificationEmail(ctx context.Context, u *user.User, locale string) {
+	const op = "service.user.sendVerificationEmail"
+
+	cfg := s.cfg.EmailVerification
+
+	token, err := s.linkSigner.Sign(purposeVerifyEmail, u.ID, u.Email, cfg.TokenTTL)
+	if err != nil {
+		s.logger.Error("failed to sign verification token", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	link, err := withQuery(cfg.URL, "token", token)
+	if err != nil {
+		s.logger.Error("invalid email verification url", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	err = s.sendMail(ctx, u.Email, locale, "email_verification", map[string]any{
+		"Username":       u.Username,
+		"Link":           link,
+		"ExpiresInHours": int(cfg.TokenTTL.Hours()),
+	})
+	if err != nil {
+		s.logger.Error("failed to send verification email", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,59 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/LullNil/authx-go/internal/lib/password"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// checkPasswordReuse rejects a new password that matches one of the user's
+// recent passwords. Hashes that can no longer be verified are skipped.
+func (s *service) checkPasswordReuse(ctx context.Context, userID int64, plain string) error {
+	if s.cfg.Password.History.Size <= 0 {
+		return nil
+	}
+
+	hashes, err := s.passwordHistory.List(ctx, userID, s.cfg.Password.History.Size, s.historyNotBefore())
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		_, err := s.passwordHasher.Verify(plain, hash)
+		if err == nil {
+			return apperr.New(http.StatusBadRequest, "password was used recently")
+		}
+		if !errors.Is(err, password.ErrMismatch) {
+			s.logger.Warn("failed to verify password history entry", slog.Int64("user_id", userID), slog.String("err", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// recordPassword adds a hash to the user's password history.
+func (s *service) recordPassword(ctx context.Context, userID int64, hash string) error {
+	if s.cfg.Password.History.Size <= 0 {
+		return nil
+	}
+
+	if err := s.passwordHistory.Add(ctx, userID, hash, s.cfg.Password.History.Size, s.historyNotBefore()); err != nil {
+		return fmt.Errorf("record password history: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) historyNotBefore() time.Time {
+	if s.cfg.Password.History.Retention <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-s.cfg.Password.History.Retention)
+}
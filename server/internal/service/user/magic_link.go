@@ -0,0 +1,142 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+var errInvalidMagicLink = apperr.New(http.StatusUnauthorized, "invalid or expired login link")
+
+// RequestMagicLink emails a single-use login link bound to req.Nonce.
+// Like ForgotPassword, the work runs in the background so the response does
+// not reveal whether the account exists.
+func (s *service) RequestMagicLink(ctx context.Context, req user.MagicLinkRequest) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !emailRegexp.MatchString(email) {
+		return apperr.New(http.StatusBadRequest, "invalid email format")
+	}
+
+	s.background(ctx, func(ctx context.Context) {
+		s.sendMagicLink(ctx, email, req.Nonce, req.Locale)
+	})
+
+	return nil
+}
+
+// LoginWithMagicLink exchanges a login link for a token pair. The link only
+// works in the browser that requested it. Following it proves ownership of
+// the address, so the email is marked as verified.
+func (s *service) LoginWithMagicLink(ctx context.Context, req user.MagicLinkLoginRequest) (*user.LoginResponse, error) {
+	const op = "service.user.LoginWithMagicLink"
+
+	t, err := s.magicLinks.GetByHash(ctx, securetoken.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidMagicLink
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+		return nil, errInvalidMagicLink
+	}
+	if !securetoken.Equal(t.NonceHash, securetoken.Hash(req.Nonce)) {
+		return nil, apperr.New(http.StatusUnauthorized, "login link must be opened in the browser that requested it")
+	}
+
+	// consume token
+	if err := s.magicLinks.MarkUsed(ctx, t.ID, now); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, errInvalidMagicLink
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errInvalidMagicLink
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if u.EmailVerifiedAt == nil {
+		if err := s.userRepo.MarkEmailVerified(ctx, u.ID, u.Email, now); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// invalidate other links
+	if err := s.magicLinks.InvalidateByUser(ctx, u.ID, now); err != nil {
+		s.logger.Warn("failed to invalidate magic links", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+
+	resp, err := s.login(ctx, u.ID, FirstFactorEmail, false, req.Client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp, nil
+}
+
+// sendMagicLink issues a login link for the user with the given email and mails it.
+// Failures are logged only: the caller has already answered the request.
+func (s *service) sendMagicLink(ctx context.Context, email, nonce, locale string) {
+	const op = "service.user.sendMagicLink"
+
+	u, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			s.logger.Error("failed to get user", slog.String("op", op), slog.String("err", err.Error()))
+		}
+		return
+	}
+
+	raw, err := securetoken.New()
+	if err != nil {
+		s.logger.Error("failed to generate magic link token", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	cfg := s.cfg.MagicLink
+	now := time.Now()
+	_, err = s.magicLinks.Save(ctx, &user.MagicLinkToken{
+		UserID:    u.ID,
+		TokenHash: securetoken.Hash(raw),
+		NonceHash: securetoken.Hash(nonce),
+		CreatedAt: now,
+		ExpiresAt: now.Add(cfg.TokenTTL),
+	})
+	if err != nil {
+		s.logger.Error("failed to save magic link token", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+		return
+	}
+
+	link, err := withQuery(cfg.URL, "token", raw)
+	if err != nil {
+		s.logger.Error("invalid magic link url", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	err = s.sendMail(ctx, u.Email, locale, "magic_link", map[string]any{
+		"Username":         u.Username,
+		"Link":             link,
+		"ExpiresInMinutes": int(cfg.TokenTTL.Minutes()),
+	})
+	if err != nil {
+		s.logger.Error("failed to send magic link", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/session"
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/password"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// First factors a login can start with, recorded on MFA challenges.
+// They are the amr values (RFC 8176) of the resulting tokens.
+const (
+	FirstFactorPassword = "pwd"
+	FirstFactorEmail    = "email"
+)
+
+// CompleteMFALogin exchanges a completed MFA challenge for a token pair.
+func (s *service) CompleteMFALogin(ctx context.Context, req mfa.CompleteChallengeRequest) (*user.LoginResponse, error) {
+	const op = "service.user.CompleteMFALogin"
+
+	c, err := s.mfaService.CompleteChallenge(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// issue tokens
+	pair, err := s.tokenService.Issue(ctx, token.IssueRequest{
+		UserID:     c.UserID,
+		Auth:       authentication(c.FirstFactor, mfa.MethodAMR(req.Method), token.AMRMultiFactor),
+		Client:     req.Client,
+		RememberMe: c.RememberMe,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return loginResponse(pair), nil
+}
+
+// Reauthenticate checks the user's credentials again within the current session
+// and returns tokens with a fresh auth_time. Users with a second factor must
+// prove it and may add their password; others enter their password. A password
+// or a second factor alone is single-factor; only both together reach
+// ACRMultiFactor.
+func (s *service) Reauthenticate(ctx context.Context, req user.ReauthenticateRequest) (*user.LoginResponse, error) {
+	const op = "service.user.Reauthenticate"
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(http.StatusNotFound, "user not found")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	methods, err := s.mfaService.Methods(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(methods) == 0 && req.Password == "" {
+		return nil, apperr.New(http.StatusBadRequest, "password is required")
+	}
+
+	var amr []string
+	if req.Password != "" {
+		if _, err := s.passwordHasher.Verify(req.Password, u.Password); err != nil {
+			if errors.Is(err, password.ErrMismatch) {
+				return nil, apperr.New(http.StatusBadRequest, "invalid password")
+			}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		amr = append(amr, FirstFactorPassword)
+	}
+
+	// check second factor
+	if len(methods) > 0 {
+		if err := s.mfaService.RequireStepUp(ctx, u.ID, req.MFA); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		amr = append(amr, mfa.MethodAMR(req.MFA.Method))
+	}
+
+	// only the password and a second factor together make it multi-factor
+	if len(amr) > 1 {
+		amr = append(amr, token.AMRMultiFactor)
+	}
+
+	pair, err := s.tokenService.Reauthenticate(ctx, u.ID, req.SessionID, authentication(amr...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return loginResponse(pair), nil
+}
+
+// login finishes a login that passed firstFactor. Users with a second factor
+// get an MFA challenge, everyone else a token pair.
+func (s *service) login(ctx context.Context, userID int64, firstFactor string, rememberMe bool, client session.Client) (*user.LoginResponse, error) {
+	methods, err := s.mfaService.Methods(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(methods) > 0 {
+		// e.g. email OTP can't complete a magic link login
+		methods = mfa.IndependentMethods(firstFactor, methods)
+		if len(methods) == 0 {
+			return nil, apperr.New(http.StatusForbidden, "log in with your password to use your second factor")
+		}
+
+		challenge, err := s.mfaService.StartChallenge(ctx, userID, firstFactor, rememberMe, methods)
+		if err != nil {
+			return nil, err
+		}
+		return &user.LoginResponse{MFA: challenge}, nil
+	}
+
+	// issue tokens
+	pair, err := s.tokenService.Issue(ctx, token.IssueRequest{
+		UserID:     userID,
+		Auth:       authentication(firstFactor),
+		Client:     client,
+		RememberMe: rememberMe,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return loginResponse(pair), nil
+}
+
+// authentication describes an authentication that just passed the given amr methods.
+func authentication(methods ...string) token.Authentication {
+	return token.Authentication{Methods: methods, Time: time.Now()}
+}
@@ -0,0 +1,54 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+// LoginWithPasskey exchanges a verified passkey assertion for a token pair.
+// The passkey required user verification, so no second factor is asked for.
+func (s *service) LoginWithPasskey(ctx context.Context, req passkey.FinishLoginRequest) (*user.LoginResponse, error) {
+	const op = "service.user.LoginWithPasskey"
+
+	userID, err := s.passkeyService.FinishLogin(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperr.New(http.StatusNotFound, "user not found")
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// require a verified email
+	if s.cfg.EmailVerification.Mode == EmailVerificationBlock && u.EmailVerifiedAt == nil {
+		return nil, apperr.New(http.StatusForbidden, "email is not verified")
+	}
+
+	// issue tokens: a passkey with user verification is something the user
+	// has unlocked with something they know or are
+	pair, err := s.tokenService.Issue(ctx, token.IssueRequest{
+		UserID: u.ID,
+		Auth:   authentication(mfa.MethodAMR(mfa.MethodWebAuthn), "user", token.AMRMultiFactor),
+		Client: req.Client,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return loginResponse(pair), nil
+}
@@ -0,0 +1,152 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LullNil/authx-go/domain/token"
+	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/securetoken"
+	"github.com/LullNil/authx-go/internal/repository"
+
+	"github.com/LullNil/go-http-utils/apperr"
+)
+
+var errInvalidResetToken = apperr.New(http.StatusBadRequest, "invalid or expired reset token")
+
+// ForgotPassword sends a password reset link to the email if it belongs to a user.
+// The lookup and delivery run in the background so that neither the response
+// nor its timing reveals whether the account exists.
+func (s *service) ForgotPassword(ctx context.Context, req user.ForgotPasswordRequest) error {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !emailRegexp.MatchString(email) {
+		return apperr.New(http.StatusBadRequest, "invalid email format")
+	}
+
+	s.background(ctx, func(ctx context.Context) {
+		s.sendPasswordReset(ctx, email, req.Locale)
+	})
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token, then invalidates
+// every other reset token and session of the user.
+func (s *service) ResetPassword(ctx context.Context, req user.ResetPasswordRequest) error {
+	const op = "service.user.ResetPassword"
+
+	// find token
+	t, err := s.passwordResets.GetByHash(ctx, securetoken.Hash(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidResetToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+		return errInvalidResetToken
+	}
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return errInvalidResetToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// validate before consuming the token, so a rejected password can be retried
+	if err := s.checkNewPassword(ctx, u, req.NewPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// consume token
+	if err := s.passwordResets.MarkUsed(ctx, t.ID, now); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return errInvalidResetToken
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.storePassword(ctx, u, req.NewPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// invalidate outstanding reset tokens and sessions
+	if err := s.passwordResets.InvalidateByUser(ctx, u.ID, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.tokenService.RevokeAll(ctx, token.RevokeRequest{UserID: u.ID}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sendPasswordReset issues a reset token for the user with the given email and mails it.
+// Failures are logged only: the caller has already answered the request.
+func (s *service) sendPasswordReset(ctx context.Context, email, locale string) {
+	const op = "service.user.sendPasswordReset"
+
+	u, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			s.logger.Error("failed to get user", slog.String("op", op), slog.String("err", err.Error()))
+		}
+		return
+	}
+
+	raw, err := securetoken.New()
+	if err != nil {
+		s.logger.Error("failed to generate reset token", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	_, err = s.passwordResets.Save(ctx, &user.PasswordResetToken{
+		UserID:    u.ID,
+		TokenHash: securetoken.Hash(raw),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.PasswordReset.TokenTTL),
+	})
+	if err != nil {
+		s.logger.Error("failed to save reset token", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+		return
+	}
+
+	link, err := withQuery(s.cfg.PasswordReset.URL, "token", raw)
+	if err != nil {
+		s.logger.Error("invalid password reset url", slog.String("op", op), slog.String("err", err.Error()))
+		return
+	}
+
+	err = s.sendMail(ctx, u.Email, locale, "password_reset", map[string]any{
+		"Username":         u.Username,
+		"Link":             link,
+		"ExpiresInMinutes": int(s.cfg.PasswordReset.TokenTTL.Minutes()),
+	})
+	if err != nil {
+		s.logger.Error("failed to send reset email", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+}
+
+// withQuery returns rawURL with the query parameter key set to value.
+func withQuery(rawURL, key, value string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -8,27 +8,102 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/LullNil/authx-go/config"
+	"github.com/LullNil/authx-go/domain/mfa"
+	"github.com/LullNil/authx-go/domain/passkey"
+	"github.com/LullNil/authx-go/domain/token"
 	"github.com/LullNil/authx-go/domain/user"
+	"github.com/LullNil/authx-go/internal/lib/mailer"
+	"github.com/LullNil/authx-go/internal/lib/password"
+	"github.com/LullNil/authx-go/internal/lib/passwordpolicy"
+	"github.com/LullNil/authx-go/internal/lib/ratelimit"
+	"github.com/LullNil/authx-go/internal/lib/signedtoken"
 	"github.com/LullNil/authx-go/internal/repository"
 
 	"github.com/LullNil/go-http-utils/apperr"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify returns password.ErrMismatch on a wrong password and reports
+	// whether the stored hash should be upgraded.
+	Verify(password, encoded string) (needsRehash bool, err error)
+}
+
+// PasswordPolicy validates new passwords.
+type PasswordPolicy interface {
+	Check(ctx context.Context, password string, userInputs ...string) ([]passwordpolicy.Violation, error)
+}
+
 type service struct {
-	userRepo user.Repository
-	logger   *slog.Logger
+	userRepo        user.Repository
+	passwordHistory user.PasswordHistoryRepository
+	passwordResets  user.PasswordResetRepository
+	emailChanges    user.EmailChangeRepository
+	magicLinks      user.MagicLinkRepository
+	tokenService    token.Service
+	mfaService      mfa.Service
+	passkeyService  passkey.Service
+	passwordHasher  PasswordHasher
+	passwordPolicy  PasswordPolicy
+	mailer          mailer.Mailer
+	mailTemplates   *mailer.Templates
+	linkSigner      *signedtoken.Signer
+	resendLimiter   *ratelimit.Limiter
+	cfg             *config.Config
+	logger          *slog.Logger
+	jobs            chan struct{}
 }
 
 // NewService returns a new user service.
-func NewService(userRepo user.Repository, logger *slog.Logger) user.Service {
+func NewService(
+	userRepo user.Repository,
+	passwordHistory user.PasswordHistoryRepository,
+	passwordResets user.PasswordResetRepository,
+	emailChanges user.EmailChangeRepository,
+	magicLinks user.MagicLinkRepository,
+	tokenService token.Service,
+	mfaService mfa.Service,
+	passkeyService passkey.Service,
+	passwordHasher PasswordHasher,
+	passwordPolicy PasswordPolicy,
+	mailer mailer.Mailer,
+	mailTemplates *mailer.Templates,
+	linkSigner *signedtoken.Signer,
+	cfg *config.Config,
+	logger *slog.Logger,
+) user.Service {
 	return &service{
-		userRepo: userRepo,
-		logger:   logger,
+		userRepo:        userRepo,
+		passwordHistory: passwordHistory,
+		passwordResets:  passwordResets,
+		emailChanges:    emailChanges,
+		magicLinks:      magicLinks,
+		tokenService:    tokenService,
+		mfaService:      mfaService,
+		passkeyService:  passkeyService,
+		passwordHasher:  passwordHasher,
+		passwordPolicy:  passwordPolicy,
+		mailer:          mailer,
+		mailTemplates:   mailTemplates,
+		linkSigner:      linkSigner,
+		resendLimiter:   ratelimit.New(1, cfg.EmailVerification.ResendInterval, cfg.RateLimit.CacheSize),
+		cfg:             cfg,
+		logger:          logger,
+		jobs:            make(chan struct{}, maxBackgroundJobs),
 	}
 }
 
+// backgroundTimeout bounds work started by background.
+const backgroundTimeout = 30 * time.Second
+
+// maxBackgroundJobs bounds the work started by background that is still
+// running. Jobs beyond it are dropped rather than queued.
+const maxBackgroundJobs = 100
+
 var (
 	usernameRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
 	emailRegexp    = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
@@ -59,8 +134,8 @@ func (s *service) RegisterUser(ctx context.Context, req user.RegisterUserRequest
 	}
 
 	// validate password
-	if len(req.Password) < 6 {
-		return 0, apperr.New(http.StatusBadRequest, "password is too weak")
+	if err := s.validatePassword(ctx, req.Password, username, email); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	// check conflicts
@@ -72,7 +147,7 @@ func (s *service) RegisterUser(ctx context.Context, req user.RegisterUserRequest
 	}
 
 	// hash password
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hash, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -80,7 +155,7 @@ func (s *service) RegisterUser(ctx context.Context, req user.RegisterUserRequest
 	id, err := s.userRepo.Save(ctx, &user.User{
 		Email:    email,
 		Username: username,
-		Password: string(hash),
+		Password: hash,
 	})
 	if err != nil {
 		if errors.Is(err, repository.ErrConflict) {
@@ -89,35 +164,246 @@ func (s *service) RegisterUser(ctx context.Context, req user.RegisterUserRequest
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// remember the initial password so it can't be reused later
+	if err := s.recordPassword(ctx, id, hash); err != nil {
+		s.logger.Warn("failed to record password history", slog.String("op", op), slog.Int64("user_id", id), slog.String("err", err.Error()))
+	}
+
+	// ask the user to confirm the email address
+	s.background(ctx, func(ctx context.Context) {
+		s.sendVerificationEmail(ctx, &user.User{ID: id, Email: email, Username: username}, req.Locale)
+	})
+
 	return id, nil
 }
 
-// LoginUser checks credentials and returns JWT stub.
-func (s *service) LoginUser(ctx context.Context, req user.LoginRequest) (string, error) {
+// LoginUser checks credentials and returns a new access/refresh token pair,
+// or an MFA challenge if the user has a second factor enabled.
+func (s *service) LoginUser(ctx context.Context, req user.LoginRequest) (*user.LoginResponse, error) {
 	const op = "service.user.LoginUser"
 
 	// normalize email
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 	if !emailRegexp.MatchString(email) {
-		return "", apperr.New(http.StatusBadRequest, "invalid email format")
+		return nil, apperr.New(http.StatusBadRequest, "invalid email format")
 	}
 
 	// get user
 	u, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return "", apperr.New(http.StatusNotFound, "user not found")
+			return nil, apperr.New(http.StatusNotFound, "user not found")
 		}
-		return "", fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	// compare password
-	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password)); err != nil {
-		return "", apperr.New(http.StatusBadRequest, "invalid login or password")
+	needsRehash, err := s.passwordHasher.Verify(req.Password, u.Password)
+	if err != nil {
+		if !errors.Is(err, password.ErrMismatch) {
+			s.logger.Error("failed to verify password hash", slog.String("op", op), slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+		}
+		return nil, apperr.New(http.StatusBadRequest, "invalid login or password")
+	}
+
+	// upgrade outdated hash
+	if needsRehash {
+		s.rehashPassword(ctx, u.ID, req.Password)
+	}
+
+	// require a verified email
+	if s.cfg.EmailVerification.Mode == EmailVerificationBlock && u.EmailVerifiedAt == nil {
+		return nil, apperr.New(http.StatusForbidden, "email is not verified")
+	}
+
+	resp, err := s.login(ctx, u.ID, FirstFactorPassword, req.RememberMe, req.Client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resp, nil
+}
+
+// RefreshTokens rotates the refresh token and returns a new token pair.
+func (s *service) RefreshTokens(ctx context.Context, req user.RefreshRequest) (*user.LoginResponse, error) {
+	const op = "service.user.RefreshTokens"
+
+	pair, err := s.tokenService.Refresh(ctx, req.RefreshToken, req.Client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return loginResponse(pair), nil
+}
+
+// LogoutUser revokes the current session, or every session of the user.
+func (s *service) LogoutUser(ctx context.Context, req user.LogoutRequest) error {
+	const op = "service.user.LogoutUser"
+
+	revokeReq := token.RevokeRequest{
+		UserID:         req.UserID,
+		SessionID:      req.SessionID,
+		TokenID:        req.TokenID,
+		TokenExpiresAt: req.TokenExpiresAt,
+	}
+
+	revoke := s.tokenService.Revoke
+	if req.AllSessions {
+		revoke = s.tokenService.RevokeAll
+	}
+
+	if err := revoke(ctx, revokeReq); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ChangePassword replaces the password of an authenticated user after
+// checking the current one, and optionally ends the user's other sessions.
+func (s *service) ChangePassword(ctx context.Context, req user.ChangePasswordRequest) error {
+	const op = "service.user.ChangePassword"
+
+	// get user
+	u, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperr.New(http.StatusNotFound, "user not found")
+		}
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	// JWT stub
-	return "fake-jwt-token", nil
+	// check current password
+	if _, err := s.passwordHasher.Verify(req.CurrentPassword, u.Password); err != nil {
+		if errors.Is(err, password.ErrMismatch) {
+			return apperr.New(http.StatusBadRequest, "invalid current password")
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// check second factor
+	if err := s.mfaService.RequireStepUp(ctx, u.ID, req.MFA); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return apperr.New(http.StatusBadRequest, "new password must differ from the current one")
+	}
+
+	if err := s.checkNewPassword(ctx, u, req.NewPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.storePassword(ctx, u, req.NewPassword); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// kick out other sessions
+	if req.RevokeOtherSessions {
+		err := s.tokenService.RevokeOthers(ctx, token.RevokeRequest{
+			UserID:    u.ID,
+			SessionID: req.SessionID,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// checkNewPassword validates a new password for the user against the
+// password policy and the user's password history.
+func (s *service) checkNewPassword(ctx context.Context, u *user.User, plain string) error {
+	if err := s.validatePassword(ctx, plain, u.Username, u.Email); err != nil {
+		return err
+	}
+	return s.checkPasswordReuse(ctx, u.ID, plain)
+}
+
+// storePassword hashes a new password of the user and records it in the history.
+func (s *service) storePassword(ctx context.Context, u *user.User, plain string) error {
+	hash, err := s.passwordHasher.Hash(plain)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, u.ID, hash); err != nil {
+		return err
+	}
+
+	if err := s.recordPassword(ctx, u.ID, hash); err != nil {
+		s.logger.Warn("failed to record password history", slog.Int64("user_id", u.ID), slog.String("err", err.Error()))
+	}
+
+	return nil
+}
+
+// validatePassword checks a new password against the password policy and
+// returns every violation at once so clients can show them together.
+func (s *service) validatePassword(ctx context.Context, password string, userInputs ...string) error {
+	violations, err := s.passwordPolicy.Check(ctx, password, userInputs...)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return apperr.NewWithData(http.StatusBadRequest, "password does not meet requirements", violations)
+	}
+	return nil
+}
+
+// rehashPassword replaces the user's hash with one made by the current hasher.
+// Failures are logged only: the login itself already succeeded.
+func (s *service) rehashPassword(ctx context.Context, userID int64, plain string) {
+	const op = "service.user.rehashPassword"
+
+	hash, err := s.passwordHasher.Hash(plain)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(ctx, userID, hash)
+	}
+	if err != nil {
+		s.logger.Warn("failed to upgrade password hash", slog.String("op", op), slog.Int64("user_id", userID), slog.String("err", err.Error()))
+		return
+	}
+
+	s.logger.Info("password hash upgraded", slog.String("op", op), slog.Int64("user_id", userID))
+}
+
+// background runs fn after the request has been answered, e.g. to send mail
+// without delaying the response or revealing whether an account exists.
+// When too many jobs are running fn is dropped, so a flood of requests
+// cannot pile up goroutines.
+func (s *service) background(ctx context.Context, fn func(ctx context.Context)) {
+	select {
+	case s.jobs <- struct{}{}:
+	default:
+		s.logger.Warn("too many background jobs, dropping one", slog.String("op", "service.user.background"))
+		return
+	}
+
+	go func() {
+		defer func() { <-s.jobs }()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// sendMail renders the named mail template in the given locale and sends it.
+func (s *service) sendMail(ctx context.Context, to, locale, name string, data any) error {
+	msg, err := s.mailTemplates.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return s.mailer.Send(ctx, msg)
+}
+
+func loginResponse(pair *token.Pair) *user.LoginResponse {
+	return &user.LoginResponse{
+		AccessToken:      pair.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        pair.AccessExpiresAt,
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+	}
 }
 
 // GetUserByID retrieves an user by ID from the database.
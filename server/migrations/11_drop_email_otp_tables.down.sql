@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_otp_codes;
+DROP TABLE IF EXISTS mfa_email;
\ No newline at end of file
@@ -0,0 +1,13 @@
+ALTER TABLE email_otp_codes RENAME TO otp_codes;
+ALTER TABLE otp_codes ADD COLUMN IF NOT EXISTS channel VARCHAR(8) NOT NULL DEFAULT 'email';
+ALTER TABLE otp_codes DROP CONSTRAINT IF EXISTS email_otp_codes_user_id_purpose_key;
+ALTER TABLE otp_codes ADD CONSTRAINT otp_codes_user_id_channel_purpose_key UNIQUE (user_id, channel, purpose);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(16);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS users_verified_phone_key ON users (phone) WHERE phone_verified_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS mfa_sms (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
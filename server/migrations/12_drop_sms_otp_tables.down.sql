@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS mfa_sms;
+
+DROP INDEX IF EXISTS users_verified_phone_key;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
+
+DELETE FROM otp_codes WHERE channel <> 'email';
+ALTER TABLE otp_codes DROP CONSTRAINT IF EXISTS otp_codes_user_id_channel_purpose_key;
+ALTER TABLE otp_codes ADD CONSTRAINT email_otp_codes_user_id_purpose_key UNIQUE (user_id, purpose);
+ALTER TABLE otp_codes DROP COLUMN IF EXISTS channel;
+ALTER TABLE otp_codes RENAME TO email_otp_codes;
\ No newline at end of file
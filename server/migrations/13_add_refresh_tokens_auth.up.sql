@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
+
+-- sessions from before amr was recorded count as single-factor logins at their start
+UPDATE refresh_tokens r
+SET auth_time = (SELECT MIN(created_at) FROM refresh_tokens f WHERE f.family_id = r.family_id)
+WHERE auth_time IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN auth_time SET NOT NULL;
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    browser VARCHAR(64) NOT NULL DEFAULT '',
+    os VARCHAR(64) NOT NULL DEFAULT '',
+    device VARCHAR(16) NOT NULL DEFAULT 'unknown',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- sessions that started before this table existed, without device details
+INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at)
+SELECT family_id, MIN(user_id), MIN(created_at), MAX(created_at), MAX(family_expires_at)
+FROM refresh_tokens
+WHERE revoked_at IS NULL AND family_expires_at > NOW()
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
\ No newline at end of file
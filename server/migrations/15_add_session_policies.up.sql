@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT false;
+-- 0 means no idle timeout: sessions from before this column only end at
+-- their absolute expiry, and their refresh tokens live as long as the session
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS idle_timeout_seconds INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE mfa_challenges ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE mfa_challenges DROP COLUMN IF EXISTS remember_me;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS idle_timeout_seconds;
+ALTER TABLE sessions DROP COLUMN IF EXISTS remember_me;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- wrong codes in a row, reset by the next accepted code
+ALTER TABLE mfa_totp ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mfa_totp ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
\ No newline at end of file
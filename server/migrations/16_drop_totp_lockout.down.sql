@@ -0,0 +1,2 @@
+ALTER TABLE mfa_totp DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE mfa_totp DROP COLUMN IF EXISTS attempts;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_phone VARCHAR(16);
+
+-- a number only becomes the user's phone once it is verified
+UPDATE users SET pending_phone = phone, phone = NULL WHERE phone IS NOT NULL AND phone_verified_at IS NULL;
\ No newline at end of file
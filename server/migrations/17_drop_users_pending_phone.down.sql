@@ -0,0 +1,3 @@
+UPDATE users SET phone = pending_phone WHERE phone IS NULL AND pending_phone IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS pending_phone;
\ No newline at end of file